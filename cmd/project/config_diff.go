@@ -0,0 +1,230 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	configDiffFormatText     = "text"
+	configDiffFormatJson     = "json"
+	configDiffFormatMarkdown = "markdown"
+
+	configDiffMaxTableValueLength = 80
+)
+
+type configDiff []ConfigChange
+
+// sorted returns the changes ordered by applier, target and field, so the output is stable.
+func (d configDiff) sorted() configDiff {
+	changes := make(configDiff, len(d))
+	copy(changes, d)
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Applier != changes[j].Applier {
+			return changes[i].Applier < changes[j].Applier
+		}
+
+		if changes[i].Target != changes[j].Target {
+			return changes[i].Target < changes[j].Target
+		}
+
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes
+}
+
+func (d configDiff) conflicts() int {
+	count := 0
+
+	for _, change := range d {
+		if change.Conflict {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (d configDiff) Render(w io.Writer, format string) error {
+	changes := d.sorted()
+
+	switch format {
+	case configDiffFormatText:
+		return changes.renderText(w)
+	case configDiffFormatJson:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(changes)
+	case configDiffFormatMarkdown:
+		return changes.renderMarkdown(w)
+	}
+
+	return fmt.Errorf("unsupported format %q, supported are %s, %s and %s", format, configDiffFormatText, configDiffFormatJson, configDiffFormatMarkdown)
+}
+
+func (d configDiff) renderText(w io.Writer) error {
+	if len(d) == 0 {
+		_, err := fmt.Fprintln(w, "Configuration is up to date")
+
+		return err
+	}
+
+	for _, change := range d {
+		unified, err := change.unifiedDiff()
+		if err != nil {
+			return err
+		}
+
+		if change.Conflict {
+			unified = fmt.Sprintf("# conflict: %s/%s/%s has been changed in the shop since the last push\n", change.Applier, change.Target, change.Field) + unified
+		}
+
+		if _, err := io.WriteString(w, unified); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d configDiff) renderMarkdown(w io.Writer) error {
+	var sb strings.Builder
+
+	if len(d) == 0 {
+		sb.WriteString("No configuration changes.\n")
+
+		_, err := io.WriteString(w, sb.String())
+
+		return err
+	}
+
+	sb.WriteString(fmt.Sprintf("### Configuration changes (%d)\n", len(d)))
+
+	if conflicts := d.conflicts(); conflicts > 0 {
+		sb.WriteString(fmt.Sprintf("\n> **%d conflicts**: the fields marked with ⚠️ have been changed in the shop since the last push.\n", conflicts))
+	}
+
+	applier := ""
+
+	for _, change := range d {
+		if change.Applier != applier {
+			applier = change.Applier
+
+			sb.WriteString(fmt.Sprintf("\n#### %s\n\n", applier))
+			sb.WriteString("| Target | Field | Before | After |\n")
+			sb.WriteString("|--------|-------|--------|-------|\n")
+		}
+
+		field := markdownCell(change.Field)
+		if change.Conflict {
+			field = "⚠️ " + field
+		}
+
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", markdownCell(change.Target), field, markdownValue(change.Before), markdownValue(change.After)))
+	}
+
+	details := make([]string, 0)
+
+	for _, change := range d {
+		if !change.isMultiline() {
+			continue
+		}
+
+		unified, err := change.unifiedDiff()
+		if err != nil {
+			return err
+		}
+
+		details = append(details, fmt.Sprintf("<details>\n<summary>%s: %s %s</summary>\n\n```diff\n%s```\n\n</details>\n", change.Applier, change.Target, change.Field, unified))
+	}
+
+	if len(details) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(strings.Join(details, "\n"))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+func (c ConfigChange) unifiedDiff() (string, error) {
+	name := fmt.Sprintf("%s/%s/%s", c.Applier, c.Target, c.Field)
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        valueLines(c.Before),
+		B:        valueLines(c.After),
+		FromFile: "shop/" + name,
+		ToFile:   "local/" + name,
+		Context:  3,
+	})
+}
+
+func (c ConfigChange) isMultiline() bool {
+	return strings.Contains(formatDiffValue(c.Before), "\n") || strings.Contains(formatDiffValue(c.After), "\n")
+}
+
+// formatDiffValue formats strings as they are and other values as JSON. Missing values are empty.
+func formatDiffValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(content)
+}
+
+func valueLines(value interface{}) []string {
+	if value == nil {
+		return []string{}
+	}
+
+	lines := strings.SplitAfter(formatDiffValue(value), "\n")
+
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n"
+	}
+
+	return lines
+}
+
+func markdownValue(value interface{}) string {
+	if value == nil {
+		return "_(not set)_"
+	}
+
+	content, err := json.Marshal(value)
+	if err != nil {
+		content = []byte(fmt.Sprintf("%v", value))
+	}
+
+	text := []rune(string(content))
+
+	if len(text) > configDiffMaxTableValueLength {
+		text = append(text[:configDiffMaxTableValueLength], '…')
+	}
+
+	return "`" + markdownCell(string(text)) + "`"
+}
+
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", "\\|")
+}
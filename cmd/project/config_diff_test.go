@@ -0,0 +1,96 @@
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfigDiff() configDiff {
+	return configDiff{
+		{Applier: "theme", Target: "Storefront", Field: "sw-color-brand-primary", Before: "#008490", After: "#ff0000"},
+		{Applier: "system_config", Target: "default", Field: "core.basicInformation.shopName", Before: "Demo", After: "My | Shop"},
+		{Applier: "mail_template", Target: "order_confirmation_mail (English)", Field: "contentPlain", Before: "Hello\nWorld\n", After: "Hello\nShop\n"},
+		{Applier: "entity", Target: "tax", Field: "taxRate", Before: nil, After: 19},
+	}
+}
+
+func TestConfigDiffText(t *testing.T) {
+	var buf bytes.Buffer
+
+	assert.NoError(t, testConfigDiff().Render(&buf, configDiffFormatText))
+
+	output := buf.String()
+
+	assert.Contains(t, output, "--- shop/system_config/default/core.basicInformation.shopName\n+++ local/system_config/default/core.basicInformation.shopName\n@@ -1 +1 @@\n-Demo\n+My | Shop\n")
+	assert.Contains(t, output, "@@ -1,2 +1,2 @@\n Hello\n-World\n+Shop\n")
+	assert.Contains(t, output, "@@ -0,0 +1 @@\n+19\n")
+
+	// entity is sorted before mail_template
+	assert.Less(t, bytes.Index(buf.Bytes(), []byte("entity/tax")), bytes.Index(buf.Bytes(), []byte("mail_template/")))
+}
+
+func TestConfigDiffJson(t *testing.T) {
+	var buf bytes.Buffer
+
+	assert.NoError(t, testConfigDiff().Render(&buf, configDiffFormatJson))
+
+	var changes []ConfigChange
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &changes))
+	assert.Len(t, changes, 4)
+	assert.Equal(t, "entity", changes[0].Applier)
+	assert.Nil(t, changes[0].Before)
+	assert.Equal(t, float64(19), changes[0].After)
+}
+
+func TestConfigDiffMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+
+	assert.NoError(t, testConfigDiff().Render(&buf, configDiffFormatMarkdown))
+
+	output := buf.String()
+
+	assert.Contains(t, output, "### Configuration changes (4)")
+	assert.Contains(t, output, "#### system_config\n\n| Target | Field | Before | After |")
+	assert.Contains(t, output, "| default | core.basicInformation.shopName | `\"Demo\"` | `\"My \\| Shop\"` |")
+	assert.Contains(t, output, "| tax | taxRate | _(not set)_ | `19` |")
+	assert.Contains(t, output, "```diff\n--- shop/mail_template/order_confirmation_mail (English)/contentPlain")
+}
+
+func TestConfigDiffEmpty(t *testing.T) {
+	var buf bytes.Buffer
+
+	assert.NoError(t, configDiff{}.Render(&buf, configDiffFormatText))
+	assert.Equal(t, "Configuration is up to date\n", buf.String())
+
+	buf.Reset()
+
+	assert.NoError(t, configDiff{}.Render(&buf, configDiffFormatJson))
+	assert.Equal(t, "[]\n", buf.String())
+
+	assert.Error(t, configDiff{}.Render(&buf, "yaml"))
+}
+
+func TestConfigDiffConflicts(t *testing.T) {
+	diff := testConfigDiff()
+	diff[1].Conflict = true
+
+	var buf bytes.Buffer
+
+	assert.NoError(t, diff.Render(&buf, configDiffFormatText))
+	assert.Contains(t, buf.String(), "# conflict: system_config/default/core.basicInformation.shopName has been changed in the shop since the last push\n--- shop/system_config/")
+
+	buf.Reset()
+
+	assert.NoError(t, diff.Render(&buf, configDiffFormatMarkdown))
+	assert.Contains(t, buf.String(), "> **1 conflicts**")
+	assert.Contains(t, buf.String(), "| default | ⚠️ core.basicInformation.shopName |")
+
+	buf.Reset()
+
+	assert.NoError(t, diff.Render(&buf, configDiffFormatJson))
+	assert.Contains(t, buf.String(), `"conflict": true`)
+	assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte(`"conflict"`)))
+}
@@ -45,6 +45,10 @@ func NewSyncApplyers(cfg *shop.Config) []ConfigSyncApplyer {
 			shop.SyncOptionMailTemplate,
 			shop.SyncOptionSystemConfig,
 			shop.SyncOptionTheme,
+			shop.SyncOptionSnippet,
+			shop.SyncOptionCms,
+			shop.SyncOptionRule,
+			shop.SyncOptionFlow,
 		}
 	}
 
@@ -58,22 +62,113 @@ func NewSyncApplyers(cfg *shop.Config) []ConfigSyncApplyer {
 			syncApplyers = append(syncApplyers, &MailTemplateSync{})
 		case shop.SyncOptionEntity:
 			syncApplyers = append(syncApplyers, &EntitySync{})
+		case shop.SyncOptionSnippet:
+			syncApplyers = append(syncApplyers, &SnippetSync{})
+		case shop.SyncOptionCms:
+			syncApplyers = append(syncApplyers, &CmsSync{})
+		case shop.SyncOptionRule:
+			syncApplyers = append(syncApplyers, &RuleSync{})
+		case shop.SyncOptionFlow:
+			syncApplyers = append(syncApplyers, &FlowSync{})
 		}
 	}
 
 	return syncApplyers
 }
 
+// buildConfigSyncOperation collects the changes of all enabled appliers.
+func buildConfigSyncOperation(ctx adminSdk.ApiContext, client *adminSdk.Client, cfg *shop.Config) (*ConfigSyncOperation, error) {
+	return buildConfigSyncOperationKeepingRemote(ctx, client, cfg, nil)
+}
+
+// buildConfigSyncOperationKeepingRemote builds the operation without the changes of the given fields, which keep their remote value.
+func buildConfigSyncOperationKeepingRemote(ctx adminSdk.ApiContext, client *adminSdk.Client, cfg *shop.Config, keep map[configStateKey]bool) (*ConfigSyncOperation, error) {
+	operation := NewConfigSyncOperation()
+
+	for key := range keep {
+		operation.keepRemote[key] = true
+	}
+
+	if cfg.Sync == nil {
+		return operation, nil
+	}
+
+	for _, applyer := range NewSyncApplyers(cfg) {
+		if err := applyer.Push(ctx, client, cfg, operation); err != nil {
+			return nil, err
+		}
+	}
+
+	return operation, nil
+}
+
 type ConfigSyncOperation struct {
 	Operations     Operation
 	SystemSettings SystemConfig
 	ThemeSettings  ThemeSettings
+	// Changes are the field-level differences between the shop and the local config, used by project config diff
+	Changes []ConfigChange
+	// MediaUploads are local files referenced in the config, which do not exist in the shop yet
+	MediaUploads []MediaUpload
+	// lookups caches the ids resolved from entity lookups during this run
+	lookups map[string]string
+	// media caches the media ids of the local files by path
+	media map[string]string
+	// remote holds the remote value of every field managed by the config, which is hashed into the lock file
+	remote map[configStateKey]interface{}
+	// keepRemote are the fields, which are not written, because the remote value is kept on a conflict
+	keepRemote map[configStateKey]bool
+}
+
+// ConfigChange describes a single field which differs between the shop and the local config.
+type ConfigChange struct {
+	Applier string      `json:"applier"`
+	Target  string      `json:"target"`
+	Field   string      `json:"field"`
+	Before  interface{} `json:"before"`
+	After   interface{} `json:"after"`
+	// Conflict is set, when the field has been changed in the shop since the last push
+	Conflict bool `json:"conflict,omitempty"`
+}
+
+func NewConfigSyncOperation() *ConfigSyncOperation {
+	return &ConfigSyncOperation{
+		Operations:     map[string]adminSdk.SyncOperation{},
+		SystemSettings: map[*string]map[string]interface{}{},
+		ThemeSettings:  []ThemeSyncOperation{},
+		Changes:        []ConfigChange{},
+		MediaUploads:   []MediaUpload{},
+		lookups:        map[string]string{},
+		media:          map[string]string{},
+		remote:         map[configStateKey]interface{}{},
+		keepRemote:     map[configStateKey]bool{},
+	}
+}
+
+func (o *ConfigSyncOperation) AddChange(applier, target, field string, before, after interface{}) {
+	o.Changes = append(o.Changes, ConfigChange{
+		Applier: applier,
+		Target:  target,
+		Field:   field,
+		Before:  before,
+		After:   after,
+	})
+}
+
+// TrackRemote records the remote value of a field managed by the config, it is compared with the lock file on the next push.
+func (o *ConfigSyncOperation) TrackRemote(applier, target, field string, value interface{}) {
+	o.remote[configStateKey{Applier: applier, Target: target, Field: field}] = value
+}
+
+// KeepsRemote reports whether the remote value of the field is kept, so the local value must not be written.
+func (o *ConfigSyncOperation) KeepsRemote(applier, target, field string) bool {
+	return o.keepRemote[configStateKey{Applier: applier, Target: target, Field: field}]
 }
 
 type ThemeSyncOperation struct {
-	Id       string
-	Name     string
-	Settings map[string]adminSdk.ThemeConfigValue
+	Id       string                               `json:"id"`
+	Name     string                               `json:"name"`
+	Settings map[string]adminSdk.ThemeConfigValue `json:"settings"`
 }
 
 type (
@@ -83,13 +178,41 @@ type (
 )
 
 func (o ConfigSyncOperation) HasChanges() bool {
-	return o.Operations.HasChanges() || o.SystemSettings.HasChanges() || o.ThemeSettings.HasChanges()
+	return o.Operations.HasChanges() || o.SystemSettings.HasChanges() || o.ThemeSettings.HasChanges() || len(o.MediaUploads) > 0
 }
 
 func (o Operation) HasChanges() bool {
 	return len(o) > 0
 }
 
+// syncPhase orders the sync operations. The sync api writes the operations in the order of their keys.
+type syncPhase int
+
+const (
+	// rules are written before the flows using them
+	syncPhaseRule syncPhase = iota + 1
+	syncPhaseFlow
+	// rules and layouts are written before the entities, so an entity can refer to a rule or layout created in the same push
+	syncPhaseCms
+	syncPhaseEntity
+	syncPhaseMailTemplate
+	syncPhaseSnippet
+	// the phases of a snapshot recreate the deleted records, restore the changed ones and delete the created ones
+	syncPhaseRestoreDeleted
+	syncPhaseRestore
+	syncPhaseDeleteCreated
+)
+
+// add adds the sync operation to the phase. Operations of the same phase are written in the order they have been added.
+func (o Operation) add(phase syncPhase, op adminSdk.SyncOperation) {
+	o[fmt.Sprintf("%02d-%06d-%s-%s", phase, len(o), op.Action, op.Entity)] = op
+}
+
+// AddOperation adds the sync operation to the phase, an applier adds the upserts of records before the deletes of their removed children.
+func (o *ConfigSyncOperation) AddOperation(phase syncPhase, op adminSdk.SyncOperation) {
+	o.Operations.add(phase, op)
+}
+
 func (t ThemeSettings) HasChanges() bool {
 	for _, m := range t {
 		if len(m.Settings) > 0 {
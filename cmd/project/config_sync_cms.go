@@ -0,0 +1,517 @@
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+	"gopkg.in/yaml.v3"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+const cmsPageSize = 10
+
+type cmsChild struct {
+	Field  string
+	Entity string
+}
+
+// cmsFields are the fields written into the layout files, in the order of the file.
+var cmsFields = map[string][]string{
+	"cms_page":    {"id", "name", "type", "entity", "cssClass", "previewMediaId", "config", "customFields"},
+	"cms_section": {"id", "name", "type", "position", "sizingMode", "mobileBehavior", "backgroundColor", "backgroundMediaId", "backgroundMediaMode", "cssClass", "visibility", "customFields"},
+	"cms_block":   {"id", "name", "type", "position", "sectionPosition", "marginTop", "marginBottom", "marginLeft", "marginRight", "backgroundColor", "backgroundMediaId", "backgroundMediaMode", "cssClass", "visibility", "customFields"},
+	"cms_slot":    {"id", "slot", "type", "config", "customFields"},
+}
+
+var cmsChildren = map[string]cmsChild{
+	"cms_page":    {Field: "sections", Entity: "cms_section"},
+	"cms_section": {Field: "blocks", Entity: "cms_block"},
+	"cms_block":   {Field: "slots", Entity: "cms_slot"},
+}
+
+// cmsMediaFields hold a media id, config fields with these names hold it in their value.
+var cmsMediaFields = map[string]bool{
+	"previewMediaId":    true,
+	"backgroundMediaId": true,
+	"mediaId":           true,
+	"media":             true,
+	"backgroundMedia":   true,
+}
+
+type CmsSync struct{}
+
+func (CmsSync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, config *shop.Config, operation *ConfigSyncOperation) error {
+	if len(config.Sync.Cms) == 0 {
+		return nil
+	}
+
+	search := newEntityLookupSearch(ctx, client, operation)
+	upserts := make([]map[string]interface{}, 0)
+	deletes := make(map[string][]map[string]interface{})
+
+	for _, entry := range config.Sync.Cms {
+		local, err := readCmsLayoutFile(entry.File)
+		if err != nil {
+			return err
+		}
+
+		id, _ := local["id"].(string)
+		if id == "" {
+			return fmt.Errorf("cms layout %s has no id", entry.File)
+		}
+
+		resolved, err := resolveEntityLookups(local, operation.lookups, search)
+		if err != nil {
+			return fmt.Errorf("cms layout %s: %w", entry.File, err)
+		}
+
+		if resolved, err = resolveMediaReferences(ctx, client, resolved, operation); err != nil {
+			return fmt.Errorf("cms layout %s: %w", entry.File, err)
+		}
+
+		desired, err := normalizeRecord(resolved)
+		if err != nil {
+			return err
+		}
+
+		desired = cleanCmsRecord("cms_page", desired)
+
+		remote, err := fetchCmsPages(ctx, client, map[string]interface{}{"ids": []string{id}})
+		if err != nil {
+			return err
+		}
+
+		var current map[string]interface{}
+
+		if len(remote) > 0 {
+			current = cleanCmsRecord("cms_page", remote[0])
+		}
+
+		name, _ := desired["name"].(string)
+		if name == "" {
+			name = id
+		}
+
+		operation.TrackRemote(shop.SyncOptionCms, name, "layout", current)
+
+		if reflect.DeepEqual(current, desired) || operation.KeepsRemote(shop.SyncOptionCms, name, "layout") {
+			continue
+		}
+
+		if current == nil {
+			operation.AddChange(shop.SyncOptionCms, name, "layout", nil, desired)
+		} else {
+			operation.AddChange(shop.SyncOptionCms, name, "layout", current, desired)
+
+			for entity, ids := range removedCmsIds("cms_page", current, desired) {
+				for _, removed := range ids {
+					deletes[entity] = append(deletes[entity], map[string]interface{}{"id": removed})
+				}
+			}
+		}
+
+		upserts = append(upserts, desired)
+	}
+
+	if len(upserts) > 0 {
+		operation.AddOperation(syncPhaseCms, adminSdk.SyncOperation{
+			Action:  "upsert",
+			Entity:  "cms_page",
+			Payload: upserts,
+		})
+	}
+
+	// the removed records are deleted after the upsert, so moved children get their new parent first
+	for _, entity := range []string{"cms_section", "cms_block", "cms_slot"} {
+		if len(deletes[entity]) > 0 {
+			operation.AddOperation(syncPhaseCms, adminSdk.SyncOperation{
+				Action:  "delete",
+				Entity:  entity,
+				Payload: deletes[entity],
+			})
+		}
+	}
+
+	return nil
+}
+
+func (CmsSync) Pull(ctx adminSdk.ApiContext, client *adminSdk.Client, config *shop.Config) error {
+	pages := make([]map[string]interface{}, 0)
+
+	for page := 1; ; page++ {
+		data, err := fetchCmsPages(ctx, client, map[string]interface{}{
+			"page":   page,
+			"limit":  cmsPageSize,
+			"sort":   []map[string]string{{"field": "name"}, {"field": "id"}},
+			"filter": []shop.EntitySyncFilter{{Type: "equals", Field: "locked", Value: false}},
+		})
+		if err != nil {
+			return err
+		}
+
+		pages = append(pages, data...)
+
+		if len(data) < cmsPageSize {
+			break
+		}
+	}
+
+	files := make(map[string]string)
+
+	for _, entry := range config.Sync.Cms {
+		if layout, err := readCmsLayoutFile(entry.File); err == nil {
+			if id, ok := layout["id"].(string); ok {
+				files[id] = entry.File
+			}
+		}
+	}
+
+	mediaIds := make(map[string]bool)
+	layouts := make([]map[string]interface{}, 0, len(pages))
+
+	for _, page := range pages {
+		layout := cleanCmsRecord("cms_page", page)
+
+		mapCmsMedia(layout, func(id string) interface{} {
+			mediaIds[id] = true
+
+			return id
+		})
+
+		layouts = append(layouts, layout)
+	}
+
+	mediaNames, err := fetchMediaFileNames(ctx, client, mediaIds)
+	if err != nil {
+		return err
+	}
+
+	usedFiles := make(map[string]bool)
+	config.Sync.Cms = make([]shop.CmsLayout, 0, len(layouts))
+
+	for _, layout := range layouts {
+		id, _ := layout["id"].(string)
+		name, _ := layout["name"].(string)
+
+		layout = mapCmsMedia(layout, func(mediaId string) interface{} {
+			fileName, ok := mediaNames[mediaId]
+			if !ok {
+				return mediaId
+			}
+
+			return map[string]interface{}{entityLookupKey: map[string]interface{}{"entity": "media", "field": "fileName", "value": fileName}}
+		}).(map[string]interface{})
+
+		file, ok := files[id]
+
+		if !ok {
+			file = fmt.Sprintf(".shopware-cli/cms/%s.yml", snippetFileName(name))
+
+			if name == "" || usedFiles[file] {
+				file = fmt.Sprintf(".shopware-cli/cms/%s-%s.yml", snippetFileName(name), id)
+			}
+		}
+
+		usedFiles[file] = true
+
+		if err := writeCmsLayoutFile(file, layout); err != nil {
+			return err
+		}
+
+		config.Sync.Cms = append(config.Sync.Cms, shop.CmsLayout{File: file})
+	}
+
+	logging.FromContext(ctx.Context).Infof("Pulled %d cms layouts", len(layouts))
+
+	return nil
+}
+
+// cleanCmsRecord returns the writable fields of the record and its children, sorted by position.
+func cleanCmsRecord(entity string, record map[string]interface{}) map[string]interface{} {
+	cleaned := make(map[string]interface{})
+
+	for _, field := range cmsFields[entity] {
+		if value, ok := record[field]; ok && value != nil {
+			cleaned[field] = value
+		}
+	}
+
+	child, ok := cmsChildren[entity]
+	if !ok {
+		return cleaned
+	}
+
+	items, _ := record[child.Field].([]interface{})
+	children := make([]interface{}, 0, len(items))
+
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			children = append(children, cleanCmsRecord(child.Entity, m))
+		}
+	}
+
+	sort.SliceStable(children, func(i, j int) bool {
+		a, b := children[i].(map[string]interface{}), children[j].(map[string]interface{})
+
+		if child.Entity == "cms_slot" {
+			return fmt.Sprintf("%v", a["slot"]) < fmt.Sprintf("%v", b["slot"])
+		}
+
+		return cmsPosition(a) < cmsPosition(b)
+	})
+
+	cleaned[child.Field] = children
+
+	return cleaned
+}
+
+func cmsPosition(record map[string]interface{}) float64 {
+	switch v := record["position"].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+
+	return 0
+}
+
+// normalizeRecord converts a record read from YAML into the types returned by the API.
+func normalizeRecord(value interface{}) (map[string]interface{}, error) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized map[string]interface{}
+
+	if err := json.Unmarshal(content, &normalized); err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
+
+// removedCmsIds returns the ids of sections, blocks and slots which exist in the shop but not in the layout. Children of removed records are deleted with them.
+func removedCmsIds(entity string, current, desired map[string]interface{}) map[string][]string {
+	removed := make(map[string][]string)
+
+	child, ok := cmsChildren[entity]
+	if !ok {
+		return removed
+	}
+
+	desiredChildren := make(map[string]map[string]interface{})
+
+	if items, ok := desired[child.Field].([]interface{}); ok {
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				if id, ok := m["id"].(string); ok {
+					desiredChildren[id] = m
+				}
+			}
+		}
+	}
+
+	items, _ := current[child.Field].([]interface{})
+
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		id, _ := m["id"].(string)
+
+		match, ok := desiredChildren[id]
+		if !ok {
+			removed[child.Entity] = append(removed[child.Entity], id)
+
+			continue
+		}
+
+		for e, ids := range removedCmsIds(child.Entity, m, match) {
+			removed[e] = append(removed[e], ids...)
+		}
+	}
+
+	return removed
+}
+
+// mapCmsMedia replaces all media ids in the layout with the result of replace.
+func mapCmsMedia(value interface{}, replace func(id string) interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if !cmsMediaFields[key] {
+				v[key] = mapCmsMedia(item, replace)
+
+				continue
+			}
+
+			if id, ok := item.(string); ok && id != "" {
+				v[key] = replace(id)
+
+				continue
+			}
+
+			if slotConfig, ok := item.(map[string]interface{}); ok && slotConfig["source"] == "static" {
+				if id, ok := slotConfig["value"].(string); ok && id != "" {
+					slotConfig["value"] = replace(id)
+				}
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = mapCmsMedia(item, replace)
+		}
+	}
+
+	return value
+}
+
+// cmsLayoutNode builds the YAML node of the record with the fields in the order of cmsFields and the children last.
+func cmsLayoutNode(entity string, record map[string]interface{}) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+
+	for _, field := range cmsFields[entity] {
+		value, ok := record[field]
+		if !ok {
+			continue
+		}
+
+		var valueNode yaml.Node
+
+		if err := valueNode.Encode(value); err != nil {
+			return nil, err
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field}, &valueNode)
+	}
+
+	child, ok := cmsChildren[entity]
+	if !ok {
+		return node, nil
+	}
+
+	children := &yaml.Node{Kind: yaml.SequenceNode}
+	items, _ := record[child.Field].([]interface{})
+
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		childNode, err := cmsLayoutNode(child.Entity, m)
+		if err != nil {
+			return nil, err
+		}
+
+		children.Content = append(children.Content, childNode)
+	}
+
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: child.Field}, children)
+
+	return node, nil
+}
+
+func readCmsLayoutFile(file string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read cms layout %s: %w", file, err)
+	}
+
+	var layout map[string]interface{}
+
+	if err := yaml.Unmarshal(content, &layout); err != nil {
+		return nil, fmt.Errorf("cannot read cms layout %s: %w", file, err)
+	}
+
+	return layout, nil
+}
+
+func writeCmsLayoutFile(file string, layout map[string]interface{}) error {
+	node, err := cmsLayoutNode("cms_page", layout)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(node); err != nil {
+		return err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, buf.Bytes(), os.ModePerm)
+}
+
+func fetchCmsPages(ctx adminSdk.ApiContext, client *adminSdk.Client, criteria map[string]interface{}) ([]map[string]interface{}, error) {
+	criteria["associations"] = map[string]interface{}{
+		"sections": map[string]interface{}{
+			"associations": map[string]interface{}{
+				"blocks": map[string]interface{}{
+					"associations": map[string]interface{}{
+						"slots": map[string]interface{}{},
+					},
+				},
+			},
+		},
+	}
+
+	return searchRecords(ctx, client, "cms_page", criteria)
+}
+
+// fetchMediaFileNames returns the file names of the media, which are used to look them up in another shop.
+func fetchMediaFileNames(ctx adminSdk.ApiContext, client *adminSdk.Client, ids map[string]bool) (map[string]string, error) {
+	names := make(map[string]string)
+
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	list := make([]string, 0, len(ids))
+
+	for id := range ids {
+		list = append(list, id)
+	}
+
+	sort.Strings(list)
+
+	records, err := searchRecords(ctx, client, "media", map[string]interface{}{
+		"ids":      list,
+		"includes": map[string][]string{"media": {"id", "fileName"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		id, _ := record["id"].(string)
+
+		if fileName, ok := record["fileName"].(string); ok && fileName != "" {
+			names[id] = fileName
+		}
+	}
+
+	return names, nil
+}
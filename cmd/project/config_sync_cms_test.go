@@ -0,0 +1,136 @@
+package project
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+func cmsTestPage() map[string]interface{} {
+	return map[string]interface{}{
+		"id":        "page",
+		"name":      "Landing",
+		"type":      "landingpage",
+		"locked":    false,
+		"createdAt": "2024-01-01",
+		"cssClass":  nil,
+		"sections": []interface{}{
+			map[string]interface{}{
+				"id":       "section-2",
+				"type":     "default",
+				"position": 1.0,
+				"pageId":   "page",
+				"blocks":   []interface{}{},
+			},
+			map[string]interface{}{
+				"id":                "section-1",
+				"type":              "default",
+				"position":          0.0,
+				"backgroundMediaId": "media-1",
+				"blocks": []interface{}{
+					map[string]interface{}{
+						"id":       "block",
+						"type":     "image",
+						"position": 0.0,
+						"slots": []interface{}{
+							map[string]interface{}{
+								"id":   "slot",
+								"slot": "image",
+								"type": "image",
+								"config": map[string]interface{}{
+									"media":       map[string]interface{}{"source": "static", "value": "media-2"},
+									"displayMode": map[string]interface{}{"source": "static", "value": "standard"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestCleanCmsRecord(t *testing.T) {
+	cleaned := cleanCmsRecord("cms_page", cmsTestPage())
+
+	assert.NotContains(t, cleaned, "locked")
+	assert.NotContains(t, cleaned, "createdAt")
+	assert.NotContains(t, cleaned, "cssClass")
+
+	sections := cleaned["sections"].([]interface{})
+
+	assert.Len(t, sections, 2)
+	assert.Equal(t, "section-1", sections[0].(map[string]interface{})["id"])
+	assert.NotContains(t, sections[1], "pageId")
+	assert.Len(t, sections[0].(map[string]interface{})["blocks"].([]interface{})[0].(map[string]interface{})["slots"], 1)
+}
+
+func TestMapCmsMedia(t *testing.T) {
+	page := cleanCmsRecord("cms_page", cmsTestPage())
+	found := make([]string, 0)
+
+	mapCmsMedia(page, func(id string) interface{} {
+		found = append(found, id)
+
+		return "mapped-" + id
+	})
+
+	assert.ElementsMatch(t, []string{"media-1", "media-2"}, found)
+
+	section := page["sections"].([]interface{})[0].(map[string]interface{})
+	slot := section["blocks"].([]interface{})[0].(map[string]interface{})["slots"].([]interface{})[0].(map[string]interface{})
+	config := slot["config"].(map[string]interface{})
+
+	assert.Equal(t, "mapped-media-1", section["backgroundMediaId"])
+	assert.Equal(t, "mapped-media-2", config["media"].(map[string]interface{})["value"])
+	assert.Equal(t, "standard", config["displayMode"].(map[string]interface{})["value"])
+}
+
+func TestRemovedCmsIds(t *testing.T) {
+	current := cleanCmsRecord("cms_page", cmsTestPage())
+	desired := cleanCmsRecord("cms_page", cmsTestPage())
+
+	assert.Empty(t, removedCmsIds("cms_page", current, desired))
+
+	sections := desired["sections"].([]interface{})
+	desired["sections"] = sections[:1]
+	sections[0].(map[string]interface{})["blocks"].([]interface{})[0].(map[string]interface{})["slots"] = []interface{}{}
+
+	assert.Equal(t, map[string][]string{"cms_section": {"section-2"}, "cms_slot": {"slot"}}, removedCmsIds("cms_page", current, desired))
+}
+
+func TestCmsLayoutFileRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cms", "landing.yml")
+	page := cleanCmsRecord("cms_page", cmsTestPage())
+
+	assert.NoError(t, writeCmsLayoutFile(file, page))
+
+	read, err := readCmsLayoutFile(file)
+	assert.NoError(t, err)
+
+	normalized, err := normalizeRecord(read)
+	assert.NoError(t, err)
+
+	assert.Equal(t, page, cleanCmsRecord("cms_page", normalized))
+}
+
+func TestCmsPushWithoutChanges(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cms", "landing.yml")
+	assert.NoError(t, writeCmsLayoutFile(file, cleanCmsRecord("cms_page", cmsTestPage())))
+
+	client, _ := newTestAdminClient(t, map[string]interface{}{
+		"/api/search/cms_page": map[string]interface{}{"data": []interface{}{cmsTestPage()}},
+	})
+
+	operation := NewConfigSyncOperation()
+	cfg := &shop.Config{Sync: &shop.ConfigSync{Cms: []shop.CmsLayout{{File: file}}}}
+
+	assert.NoError(t, CmsSync{}.Push(adminSdk.NewApiContext(context.Background()), client, cfg, operation))
+	assert.Empty(t, operation.Operations)
+	assert.Empty(t, operation.Changes)
+}
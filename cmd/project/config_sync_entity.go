@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
 
@@ -14,55 +17,531 @@ import (
 type EntitySync struct{}
 
 func (EntitySync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, config *shop.Config, operation *ConfigSyncOperation) error {
-	for _, entity := range config.Sync.Entity {
-		if entity.Exists != nil && len(*entity.Exists) > 0 {
-			criteria := make(map[string]interface{})
-			criteria["filter"] = entity.Exists
+	entities := config.Sync.Entity
 
-			searchPayload, err := json.Marshal(criteria)
+	if len(config.Sync.EntityPaths) > 0 {
+		files, err := loadEntityFiles(filepath.Dir(projectConfigPath), config.Sync.EntityPaths)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			entities = append(entities, file.Entities...)
+		}
+	}
+
+	for _, entity := range entities {
+		entity, err := resolveEntitySync(ctx, client, entity, operation)
+		if err != nil {
+			return err
+		}
+
+		if _, err := pushEntity(ctx, client, entity, operation); err != nil {
+			return err
+		}
+	}
+
+	for _, group := range config.Sync.EntityGroups {
+		keep := make(map[string]bool)
+
+		for _, entity := range group.Entities {
+			if entity.Entity == "" {
+				entity.Entity = group.Entity
+			}
+
+			entity, err := resolveEntitySync(ctx, client, entity, operation)
 			if err != nil {
 				return err
 			}
 
-			r, err := client.NewRequest(ctx, "POST", fmt.Sprintf("/api/search-ids/%s", entity.Entity), bytes.NewReader(searchPayload))
+			if group.Managed && !hasEntityIdentity(entity) {
+				return fmt.Errorf("entity group %s is managed, every entry needs an id in the payload or an exists filter", group.Name)
+			}
+
+			ids, err := pushEntity(ctx, client, entity, operation)
 			if err != nil {
 				return err
 			}
 
-			r.Header.Set("Accept", "application/json")
-			r.Header.Set("Content-Type", "application/json")
+			for _, id := range ids {
+				keep[id] = true
+			}
+		}
 
-			var res criteriaApiResponse
-			resp, err := client.Do(ctx.Context, r, &res)
-			if err != nil {
+		if group.Managed {
+			if err := pruneEntityGroup(ctx, client, group, keep, operation); err != nil {
 				return err
 			}
+		}
+	}
 
-			defer func() {
-				if err := resp.Body.Close(); err != nil {
-					logging.FromContext(ctx.Context).Errorf("Push: %v", err)
-				}
-			}()
+	return nil
+}
+
+// resolveEntitySync returns a copy of the entity with all lookups in the payload and the exists filter resolved to ids.
+func resolveEntitySync(ctx adminSdk.ApiContext, client *adminSdk.Client, entity shop.EntitySync, operation *ConfigSyncOperation) (shop.EntitySync, error) {
+	search := newEntityLookupSearch(ctx, client, operation)
+
+	payload, err := resolveEntityLookups(entity.Payload, operation.lookups, search)
+	if err != nil {
+		return entity, fmt.Errorf("entity %s: %w", entity.Entity, err)
+	}
+
+	if payload, err = resolveMediaReferences(ctx, client, payload, operation); err != nil {
+		return entity, fmt.Errorf("entity %s: %w", entity.Entity, err)
+	}
 
-			if res.Total > 0 {
-				continue
+	entity.Payload, _ = payload.(map[string]interface{})
+
+	if entity.Exists != nil {
+		exists := make([]shop.EntitySyncFilter, len(*entity.Exists))
+
+		for i, filter := range *entity.Exists {
+			if filter.Value, err = resolveEntityLookups(filter.Value, operation.lookups, search); err != nil {
+				return entity, fmt.Errorf("entity %s: exists %s: %w", entity.Entity, filter.Field, err)
 			}
+
+			exists[i] = filter
+		}
+
+		entity.Exists = &exists
+	}
+
+	return entity, nil
+}
+
+// pushEntity adds the upsert of the entity to the operation, when it does not exist or differs. It returns the ids the entry refers to.
+func pushEntity(ctx adminSdk.ApiContext, client *adminSdk.Client, entity shop.EntitySync, operation *ConfigSyncOperation) ([]string, error) {
+	ids := make([]string, 0)
+
+	if id, ok := entity.Payload["id"].(string); ok && id != "" {
+		ids = append(ids, id)
+	}
+
+	if entity.Exists != nil && len(*entity.Exists) > 0 {
+		res, err := searchEntityIds(ctx, client, entity.Entity, entity.Exists)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.Total > 0 {
+			return append(ids, res.Data...), nil
+		}
+	}
+
+	changed, err := recordEntityChanges(ctx, client, entity, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	if !changed {
+		return ids, nil
+	}
+
+	// entities are written in the order of the config
+	operation.AddOperation(syncPhaseEntity, adminSdk.SyncOperation{
+		Action:  "upsert",
+		Entity:  entity.Entity,
+		Payload: []map[string]interface{}{entity.Payload},
+	})
+
+	return ids, nil
+}
+
+// hasEntityIdentity reports whether the entry can be matched with an existing record.
+func hasEntityIdentity(entity shop.EntitySync) bool {
+	if id, ok := entity.Payload["id"].(string); ok && id != "" {
+		return true
+	}
+
+	return entity.Exists != nil && len(*entity.Exists) > 0
+}
+
+// pruneEntityGroup adds the deletion of all records matching the scope of the group, which are not kept, to the operation.
+func pruneEntityGroup(ctx adminSdk.ApiContext, client *adminSdk.Client, group shop.EntitySyncGroup, keep map[string]bool, operation *ConfigSyncOperation) error {
+	res, err := searchEntityIds(ctx, client, group.Entity, group.Scope)
+	if err != nil {
+		return err
+	}
+
+	deletes := make([]map[string]interface{}, 0)
+
+	for _, id := range res.Data {
+		if keep[id] {
+			continue
 		}
 
-		operation.Operations[shop.NewUuid()] = adminSdk.SyncOperation{
-			Action:  "upsert",
-			Entity:  entity.Entity,
-			Payload: []map[string]interface{}{entity.Payload},
+		existing, err := fetchEntity(ctx, client, group.Entity, id)
+		if err != nil {
+			return err
 		}
+
+		deletes = append(deletes, map[string]interface{}{"id": id})
+		operation.AddChange(shop.SyncOptionEntity, fmt.Sprintf("%s %s", group.Entity, id), "(deleted)", existing, nil)
+	}
+
+	if len(deletes) > 0 {
+		operation.AddOperation(syncPhaseEntity, adminSdk.SyncOperation{
+			Action:  "delete",
+			Entity:  group.Entity,
+			Payload: deletes,
+		})
 	}
 
 	return nil
 }
 
-func (EntitySync) Pull(_ adminSdk.ApiContext, _ *adminSdk.Client, _ *shop.Config) error {
+func searchEntityIds(ctx adminSdk.ApiContext, client *adminSdk.Client, entity string, filter *[]shop.EntitySyncFilter) (*criteriaApiResponse, error) {
+	criteria := make(map[string]interface{})
+
+	if filter != nil && len(*filter) > 0 {
+		criteria["filter"] = filter
+	}
+
+	r, err := client.NewRequest(ctx, "POST", fmt.Sprintf("/api/search-ids/%s", entity), criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Header.Set("Accept", "application/json")
+
+	var res criteriaApiResponse
+
+	resp, err := client.Do(ctx.Context, r, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (EntitySync) Pull(ctx adminSdk.ApiContext, client *adminSdk.Client, config *shop.Config) error {
+	if len(config.Sync.EntityPull) == 0 {
+		return nil
+	}
+
+	schema, err := fetchEntitySchema(ctx, client)
+	if err != nil {
+		logging.FromContext(ctx.Context).Warnf("Cannot read the entity schema, only known auto fields are removed: %v", err)
+	}
+
+	pulled := make([]shop.EntitySync, 0)
+
+	for _, rule := range config.Sync.EntityPull {
+		records, err := searchEntities(ctx, client, rule)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			pulled = append(pulled, shop.EntitySync{
+				Entity:  rule.Entity,
+				Exists:  entityExistsFilter(record, rule.Identifier),
+				Payload: cleanPulledEntity(record, schema[rule.Entity], rule.Fields),
+			})
+		}
+
+		logging.FromContext(ctx.Context).Infof("Pulled %d records of %s", len(records), rule.Entity)
+	}
+
+	if len(config.Sync.EntityPaths) == 0 {
+		config.Sync.Entity = mergePulledEntities(config.Sync.Entity, pulled)
+
+		return nil
+	}
+
+	baseDir := filepath.Dir(projectConfigPath)
+
+	files, err := loadEntityFiles(baseDir, config.Sync.EntityPaths)
+	if err != nil {
+		return err
+	}
+
+	dir, ext := entityPullTarget(baseDir, config.Sync.EntityPaths)
+
+	for _, file := range mergePulledEntityFiles(files, pulled, dir, ext) {
+		if err := writeEntityFile(file); err != nil {
+			return err
+		}
+
+		logging.FromContext(ctx.Context).Infof("%s has been updated", file.Path)
+	}
+
 	return nil
 }
 
+// entityAutoFields are managed by Shopware and never written into a payload.
+var entityAutoFields = map[string]bool{
+	"apiAlias":          true,
+	"autoIncrement":     true,
+	"childCount":        true,
+	"createdAt":         true,
+	"extensions":        true,
+	"translated":        true,
+	"updatedAt":         true,
+	"versionId":         true,
+	"_uniqueIdentifier": true,
+}
+
+type entitySchemaField struct {
+	Type  string                 `json:"type"`
+	Flags map[string]interface{} `json:"flags"`
+	// Relation, Entity and for many to many associations Mapping, Local and Reference describe associations
+	Relation  string `json:"relation"`
+	Entity    string `json:"entity"`
+	Mapping   string `json:"mapping"`
+	Local     string `json:"local"`
+	Reference string `json:"reference"`
+}
+
+type entitySchema struct {
+	Properties map[string]entitySchemaField `json:"properties"`
+}
+
+func fetchEntitySchema(ctx adminSdk.ApiContext, client *adminSdk.Client) (map[string]entitySchema, error) {
+	r, err := client.NewRequest(ctx, "GET", "/api/_info/entity-schema.json", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Header.Set("Accept", "application/json")
+
+	var schema map[string]entitySchema
+
+	resp, err := client.Do(ctx.Context, r, &schema)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+const entityPullPageSize = 100
+
+func searchEntities(ctx adminSdk.ApiContext, client *adminSdk.Client, rule shop.EntityPullRule) ([]map[string]interface{}, error) {
+	records := make([]map[string]interface{}, 0)
+
+	for page := 1; ; page++ {
+		criteria := map[string]interface{}{
+			"page":  page,
+			"limit": entityPullPageSize,
+			"sort":  []map[string]string{{"field": "id"}},
+		}
+
+		if rule.Filter != nil && len(*rule.Filter) > 0 {
+			criteria["filter"] = rule.Filter
+		}
+
+		if len(rule.Fields) > 0 {
+			includes := append([]string{"id"}, rule.Fields...)
+			criteria["includes"] = map[string][]string{rule.Entity: append(includes, rule.Identifier...)}
+		}
+
+		data, err := searchRecords(ctx, client, rule.Entity, criteria)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, data...)
+
+		if len(data) < entityPullPageSize {
+			return records, nil
+		}
+	}
+}
+
+// cleanPulledEntity returns the payload of a record. Without fields, read-only, computed and auto fields, associations and empty values are removed.
+func cleanPulledEntity(record map[string]interface{}, schema entitySchema, fields []string) map[string]interface{} {
+	payload := make(map[string]interface{})
+
+	if len(fields) > 0 {
+		if id, ok := record["id"]; ok {
+			payload["id"] = id
+		}
+
+		for _, field := range fields {
+			if value, ok := record[field]; ok {
+				payload[field] = value
+			}
+		}
+
+		return payload
+	}
+
+	for field, value := range record {
+		if value == nil || entityAutoFields[field] || strings.HasSuffix(field, "VersionId") {
+			continue
+		}
+
+		if definition, ok := schema.Properties[field]; ok && !isWritableEntityField(definition) {
+			continue
+		}
+
+		payload[field] = value
+	}
+
+	return payload
+}
+
+func isWritableEntityField(field entitySchemaField) bool {
+	if field.Type == "association" {
+		return false
+	}
+
+	for _, flag := range []string{"write_protected", "computed", "runtime"} {
+		if _, ok := field.Flags[flag]; ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// entityExistsFilter builds an equals filter for every identifier field of the record.
+func entityExistsFilter(record map[string]interface{}, identifier []string) *[]shop.EntitySyncFilter {
+	if len(identifier) == 0 {
+		identifier = []string{"id"}
+	}
+
+	filters := make([]shop.EntitySyncFilter, 0, len(identifier))
+
+	for _, field := range identifier {
+		filters = append(filters, shop.EntitySyncFilter{Type: "equals", Field: field, Value: record[field]})
+	}
+
+	return &filters
+}
+
+// mergePulledEntities replaces the entries with the same entity and id, other pulled entries are appended.
+func mergePulledEntities(existing, pulled []shop.EntitySync) []shop.EntitySync {
+	for _, entry := range pulled {
+		replaced := false
+
+		for i, current := range existing {
+			if current.Entity == entry.Entity && current.Payload["id"] != nil && current.Payload["id"] == entry.Payload["id"] {
+				existing[i] = entry
+				replaced = true
+
+				break
+			}
+		}
+
+		if !replaced {
+			existing = append(existing, entry)
+		}
+	}
+
+	return existing
+}
+
+// recordEntityChanges compares the payload with the stored entity, when the payload contains an id. Fields keeping their remote value are removed from the payload. It returns false when the entity is up to date.
+func recordEntityChanges(ctx adminSdk.ApiContext, client *adminSdk.Client, entity shop.EntitySync, operation *ConfigSyncOperation) (bool, error) {
+	target := entity.Entity
+	existing := map[string]interface{}{}
+	// only entities with an id can be tracked in the lock file
+	identified := false
+
+	if id, ok := entity.Payload["id"].(string); ok && id != "" {
+		target = fmt.Sprintf("%s %s", entity.Entity, id)
+		identified = true
+
+		found, err := fetchEntity(ctx, client, entity.Entity, id)
+		if err != nil {
+			return false, err
+		}
+
+		if found != nil {
+			existing = found
+		}
+	}
+
+	changed := false
+
+	for _, field := range sortedKeys(entity.Payload) {
+		before, ok := existing[field]
+
+		if identified && field != "id" {
+			operation.TrackRemote(shop.SyncOptionEntity, target, field, before)
+		}
+
+		if operation.KeepsRemote(shop.SyncOptionEntity, target, field) {
+			delete(entity.Payload, field)
+
+			continue
+		}
+
+		localJson, _ := json.Marshal(entity.Payload[field])
+		remoteJson, _ := json.Marshal(before)
+
+		if ok && bytes.Equal(localJson, remoteJson) {
+			continue
+		}
+
+		operation.AddChange(shop.SyncOptionEntity, target, field, before, entity.Payload[field])
+		changed = true
+	}
+
+	return changed, nil
+}
+
+func fetchEntity(ctx adminSdk.ApiContext, client *adminSdk.Client, entity, id string) (map[string]interface{}, error) {
+	data, err := searchRecords(ctx, client, entity, map[string]interface{}{"ids": []string{id}})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	return data[0], nil
+}
+
+// searchRecords returns the records of the entity matching the criteria as plain maps.
+func searchRecords(ctx adminSdk.ApiContext, client *adminSdk.Client, entity string, criteria map[string]interface{}) ([]map[string]interface{}, error) {
+	r, err := client.NewRequest(ctx, "POST", fmt.Sprintf("/api/search/%s", entity), criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Header.Set("Accept", "application/json")
+
+	var res struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+
+	resp, err := client.Do(ctx.Context, r, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		return nil, err
+	}
+
+	return res.Data, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
 type criteriaApiResponse struct {
 	Total int      `json:"total"`
 	Data  []string `json:"data"`
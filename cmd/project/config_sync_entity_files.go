@@ -0,0 +1,472 @@
+package project
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gobwas/glob"
+	"gopkg.in/yaml.v3"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+// entityFile is a YAML file matched by sync.entity_paths, holding one or many entity sync entries.
+type entityFile struct {
+	Path     string
+	Entities []shop.EntitySync
+	// documents are the documents of the file as read, so the file can be written in place
+	documents []entityDocument
+}
+
+// entityDocument is a YAML document of an entity file with its placeholders and comments, holding a list of entries or a single entry.
+type entityDocument struct {
+	node  *yaml.Node
+	list  bool
+	count int
+}
+
+// loadEntityFiles reads all files matching the patterns, ordered so that files defining an entity come before the files looking it up.
+func loadEntityFiles(baseDir string, patterns []string) ([]*entityFile, error) {
+	paths, err := findEntityFiles(baseDir, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*entityFile, 0, len(paths))
+
+	for _, path := range paths {
+		file, err := readEntityFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, file)
+	}
+
+	return orderEntityFiles(files)
+}
+
+// findEntityFiles returns the sorted paths of all files matching the patterns. A ** matches any number of directories, also none.
+func findEntityFiles(baseDir string, patterns []string) ([]string, error) {
+	found := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(pattern)
+
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.ToSlash(filepath.Join(baseDir, pattern))
+		}
+
+		matchers := make([]glob.Glob, 0, 2)
+
+		for _, p := range []string{pattern, strings.ReplaceAll(pattern, "/**/", "/")} {
+			matcher, err := glob.Compile(p, '/')
+			if err != nil {
+				return nil, fmt.Errorf("invalid entity path %s: %w", pattern, err)
+			}
+
+			matchers = append(matchers, matcher)
+		}
+
+		root := entityPathRoot(pattern)
+
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				return nil
+			}
+
+			for _, matcher := range matchers {
+				if matcher.Match(filepath.ToSlash(path)) {
+					found[path] = true
+
+					break
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	paths := make([]string, 0, len(found))
+
+	for path := range found {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// entityPathRoot returns the directory of the pattern before the first wildcard.
+func entityPathRoot(pattern string) string {
+	segments := strings.Split(pattern, "/")
+
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, "*?[{") {
+			root := strings.Join(segments[:i], "/")
+
+			if root == "" && strings.HasPrefix(pattern, "/") {
+				return "/"
+			}
+
+			if root == "" {
+				return "."
+			}
+
+			return filepath.FromSlash(root)
+		}
+	}
+
+	return filepath.Dir(filepath.FromSlash(pattern))
+}
+
+// readEntityFile reads all YAML documents of the file. A document is either a single entry or a list of entries.
+// The ${NAME} placeholders are replaced in the values only, the documents keep them for writing the file.
+func readEntityFile(path string) (*entityFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &entityFile{Path: path, Entities: []shop.EntitySync{}}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+
+	for {
+		var node yaml.Node
+
+		if err := decoder.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, fmt.Errorf("cannot read entity file %s: %w", path, err)
+		}
+
+		if len(node.Content) == 0 {
+			continue
+		}
+
+		expanded := expandEntityPlaceholders(&node)
+
+		if node.Content[0].Kind == yaml.SequenceNode {
+			var entities []shop.EntitySync
+
+			if err := expanded.Decode(&entities); err != nil {
+				return nil, fmt.Errorf("cannot read entity file %s: %w", path, err)
+			}
+
+			file.Entities = append(file.Entities, entities...)
+			file.documents = append(file.documents, entityDocument{node: &node, list: true, count: len(entities)})
+
+			continue
+		}
+
+		var entity shop.EntitySync
+
+		if err := expanded.Decode(&entity); err != nil {
+			return nil, fmt.Errorf("cannot read entity file %s: %w", path, err)
+		}
+
+		file.Entities = append(file.Entities, entity)
+		file.documents = append(file.documents, entityDocument{node: &node, count: 1})
+	}
+
+	for i, entity := range file.Entities {
+		if entity.Entity == "" {
+			return nil, fmt.Errorf("entity file %s: entry %d has no entity", path, i+1)
+		}
+	}
+
+	return file, nil
+}
+
+// expandEntityPlaceholders returns a copy of the node, with the placeholders of all scalars replaced.
+func expandEntityPlaceholders(node *yaml.Node) *yaml.Node {
+	expanded := *node
+
+	if expanded.Kind == yaml.ScalarNode {
+		expanded.Value = shop.ExpandEnvPlaceholders(expanded.Value)
+	}
+
+	expanded.Content = make([]*yaml.Node, 0, len(node.Content))
+
+	for _, child := range node.Content {
+		expanded.Content = append(expanded.Content, expandEntityPlaceholders(child))
+	}
+
+	return &expanded
+}
+
+// orderEntityFiles sorts the files topologically, a file looking up an entity depends on all other files defining that entity. Independent files keep their path order.
+// Lookups of entities the file defines itself are ignored, the entries of a file are synced in their order.
+func orderEntityFiles(files []*entityFile) ([]*entityFile, error) {
+	defines := make(map[string][]int)
+
+	for i, file := range files {
+		for _, entity := range file.Entities {
+			defines[entity.Entity] = append(defines[entity.Entity], i)
+		}
+	}
+
+	dependencies := make([]map[int]bool, len(files))
+
+	for i, file := range files {
+		dependencies[i] = make(map[int]bool)
+		lookedUp := make(map[string]bool)
+		own := make(map[string]bool)
+
+		for _, entity := range file.Entities {
+			own[entity.Entity] = true
+			collectLookupEntities(entity.Payload, lookedUp)
+
+			if entity.Exists != nil {
+				for _, filter := range *entity.Exists {
+					collectLookupEntities(filter.Value, lookedUp)
+				}
+			}
+		}
+
+		for name := range lookedUp {
+			if own[name] {
+				continue
+			}
+
+			for _, j := range defines[name] {
+				if j != i {
+					dependencies[i][j] = true
+				}
+			}
+		}
+	}
+
+	ordered := make([]*entityFile, 0, len(files))
+	done := make([]bool, len(files))
+
+	for len(ordered) < len(files) {
+		progressed := false
+
+		for i, file := range files {
+			if done[i] {
+				continue
+			}
+
+			ready := true
+
+			for j := range dependencies[i] {
+				if !done[j] {
+					ready = false
+
+					break
+				}
+			}
+
+			if ready {
+				ordered = append(ordered, file)
+				done[i] = true
+				progressed = true
+
+				break
+			}
+		}
+
+		if !progressed {
+			cycle := make([]string, 0)
+
+			for i, file := range files {
+				if !done[i] {
+					cycle = append(cycle, file.Path)
+				}
+			}
+
+			return nil, fmt.Errorf("entity files have circular lookups: %s", strings.Join(cycle, ", "))
+		}
+	}
+
+	return ordered, nil
+}
+
+// collectLookupEntities adds the entities of all lookups in the value.
+func collectLookupEntities(value interface{}, entities map[string]bool) {
+	if lookup, ok, _ := parseEntityLookup(value); ok {
+		if lookup != nil {
+			entities[lookup.Entity] = true
+		}
+
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, item := range v {
+			collectLookupEntities(item, entities)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectLookupEntities(item, entities)
+		}
+	}
+}
+
+// mergePulledEntityFiles puts the pulled entries into the file containing the same entity and id, or into <dir>/<entity><ext>. It returns the changed files.
+func mergePulledEntityFiles(files []*entityFile, pulled []shop.EntitySync, dir, ext string) []*entityFile {
+	changed := make(map[string]*entityFile)
+	byPath := make(map[string]*entityFile)
+
+	for _, file := range files {
+		byPath[file.Path] = file
+	}
+
+	for _, entry := range pulled {
+		target := findEntityFile(files, entry)
+
+		if target == nil {
+			path := filepath.Join(dir, entry.Entity+ext)
+
+			if target = byPath[path]; target == nil {
+				target = &entityFile{Path: path, Entities: []shop.EntitySync{}}
+				byPath[path] = target
+				files = append(files, target)
+			}
+		}
+
+		target.Entities = mergePulledEntities(target.Entities, []shop.EntitySync{entry})
+		changed[target.Path] = target
+	}
+
+	result := make([]*entityFile, 0, len(changed))
+
+	for _, file := range changed {
+		result = append(result, file)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+
+	return result
+}
+
+func findEntityFile(files []*entityFile, entry shop.EntitySync) *entityFile {
+	if entry.Payload["id"] == nil {
+		return nil
+	}
+
+	for _, file := range files {
+		for _, current := range file.Entities {
+			if current.Entity == entry.Entity && current.Payload["id"] == entry.Payload["id"] {
+				return file
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeEntityFile writes the entries into the documents they have been read from. The documents are edited in place, so comments and placeholders of unchanged values are kept.
+// New entries are added to the last document, when it is a list, otherwise as a document each. A new file gets a single list.
+func writeEntityFile(file *entityFile) error {
+	documents := make([]*yaml.Node, 0, len(file.documents))
+	offset := 0
+
+	for i, document := range file.documents {
+		count := min(document.count, len(file.Entities)-offset)
+
+		if document.list && i == len(file.documents)-1 {
+			count = len(file.Entities) - offset
+		}
+
+		if count <= 0 && !document.list {
+			continue
+		}
+
+		var updated yaml.Node
+
+		if document.list {
+			if err := updated.Encode(file.Entities[offset : offset+count]); err != nil {
+				return err
+			}
+		} else if err := updated.Encode(file.Entities[offset]); err != nil {
+			return err
+		}
+
+		document.node.Content[0] = shop.MergeYamlNode(document.node.Content[0], &updated)
+		documents = append(documents, document.node)
+		offset += count
+	}
+
+	if remaining := file.Entities[offset:]; len(remaining) > 0 {
+		if len(file.documents) == 0 {
+			var node yaml.Node
+
+			if err := node.Encode(remaining); err != nil {
+				return err
+			}
+
+			documents = append(documents, &node)
+		} else {
+			for _, entity := range remaining {
+				var node yaml.Node
+
+				if err := node.Encode(entity); err != nil {
+					return err
+				}
+
+				documents = append(documents, &node)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	for _, document := range documents {
+		if err := encoder.Encode(document); err != nil {
+			return err
+		}
+	}
+
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file.Path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(file.Path, buf.Bytes(), os.ModePerm)
+}
+
+// entityPullTarget returns the directory and file extension new entity files are written to.
+func entityPullTarget(baseDir string, patterns []string) (string, string) {
+	pattern := filepath.ToSlash(patterns[0])
+
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.ToSlash(filepath.Join(baseDir, pattern))
+	}
+
+	ext := ".yml"
+
+	if strings.HasSuffix(pattern, ".yaml") {
+		ext = ".yaml"
+	}
+
+	return entityPathRoot(pattern), ext
+}
@@ -0,0 +1,192 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+func TestLoadEntityFiles(t *testing.T) {
+	dir := t.TempDir()
+	entities := filepath.Join(dir, "config", "sync", "entities")
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(entities, "shipping"), os.ModePerm))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(entities, "shipping", "prices.yml"), []byte(`entity: shipping_method_price
+payload:
+  currencyId:
+    lookup:
+      entity: currency
+      field: isoCode
+      value: CHF
+---
+entity: shipping_method_price
+payload:
+  price: 1
+`), os.ModePerm))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(entities, "currency.yml"), []byte(`- entity: currency
+  payload:
+    isoCode: CHF
+- entity: currency
+  payload:
+    isoCode: USD
+`), os.ModePerm))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(entities, "ignored.txt"), []byte("foo"), os.ModePerm))
+
+	files, err := loadEntityFiles(dir, []string{"config/sync/entities/**/*.yml"})
+
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, filepath.Join(entities, "currency.yml"), files[0].Path)
+	assert.Len(t, files[0].Entities, 2)
+	assert.Equal(t, filepath.Join(entities, "shipping", "prices.yml"), files[1].Path)
+	assert.Len(t, files[1].Entities, 2)
+
+	t.Run("missing directory", func(t *testing.T) {
+		files, err := loadEntityFiles(dir, []string{"missing/*.yml"})
+
+		assert.NoError(t, err)
+		assert.Len(t, files, 0)
+	})
+}
+
+func TestOrderEntityFiles(t *testing.T) {
+	lookup := func(entity string) map[string]interface{} {
+		return map[string]interface{}{"lookup": map[string]interface{}{"entity": entity, "field": "name", "value": "x"}}
+	}
+
+	a := &entityFile{Path: "a.yml", Entities: []shop.EntitySync{{Entity: "product", Payload: map[string]interface{}{"taxId": lookup("tax")}}}}
+	b := &entityFile{Path: "b.yml", Entities: []shop.EntitySync{{Entity: "currency"}}}
+	c := &entityFile{Path: "c.yml", Entities: []shop.EntitySync{{Entity: "tax", Payload: map[string]interface{}{"name": "x"}}}}
+
+	ordered, err := orderEntityFiles([]*entityFile{a, b, c})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*entityFile{b, c, a}, ordered)
+
+	t.Run("circular", func(t *testing.T) {
+		d := &entityFile{Path: "d.yml", Entities: []shop.EntitySync{{Entity: "tax", Payload: map[string]interface{}{"productId": lookup("product")}}}}
+
+		_, err := orderEntityFiles([]*entityFile{a, d})
+
+		assert.ErrorContains(t, err, "circular")
+	})
+
+	t.Run("lookups of own entities", func(t *testing.T) {
+		e := &entityFile{Path: "e.yml", Entities: []shop.EntitySync{
+			{Entity: "category", Payload: map[string]interface{}{"name": "Root"}},
+			{Entity: "category", Payload: map[string]interface{}{"parentId": lookup("category")}},
+		}}
+		f := &entityFile{Path: "f.yml", Entities: []shop.EntitySync{
+			{Entity: "category", Payload: map[string]interface{}{"name": "Shoes", "parentId": lookup("category")}},
+		}}
+
+		ordered, err := orderEntityFiles([]*entityFile{e, f})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*entityFile{e, f}, ordered)
+	})
+}
+
+func TestMergePulledEntityFiles(t *testing.T) {
+	existing := &entityFile{Path: "entities/taxes.yml", Entities: []shop.EntitySync{
+		{Entity: "tax", Payload: map[string]interface{}{"id": "a", "name": "Old"}},
+	}}
+
+	changed := mergePulledEntityFiles([]*entityFile{existing}, []shop.EntitySync{
+		{Entity: "tax", Payload: map[string]interface{}{"id": "a", "name": "New"}},
+		{Entity: "currency", Payload: map[string]interface{}{"id": "b"}},
+		{Entity: "currency", Payload: map[string]interface{}{"id": "c"}},
+	}, "entities", ".yml")
+
+	assert.Len(t, changed, 2)
+	assert.Equal(t, filepath.Join("entities", "currency.yml"), changed[0].Path)
+	assert.Len(t, changed[0].Entities, 2)
+	assert.Equal(t, "entities/taxes.yml", changed[1].Path)
+	assert.Equal(t, "New", changed[1].Entities[0].Payload["name"])
+}
+
+func TestWriteEntityFileInPlace(t *testing.T) {
+	t.Setenv("TAX_NAME", "Secret Tax")
+
+	path := filepath.Join(t.TempDir(), "taxes.yml")
+
+	assert.NoError(t, os.WriteFile(path, []byte(`# the standard tax
+entity: tax
+payload:
+  id: a
+  name: ${TAX_NAME} # from the environment
+  taxRate: 19
+---
+entity: tax
+payload:
+  id: b
+  name: Costs $5
+  taxRate: 7
+`), os.ModePerm))
+
+	file, err := readEntityFile(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Secret Tax", file.Entities[0].Payload["name"])
+	assert.Equal(t, "Costs $5", file.Entities[1].Payload["name"])
+
+	file.Entities = mergePulledEntities(file.Entities, []shop.EntitySync{
+		{Entity: "tax", Payload: map[string]interface{}{"id": "a", "name": "Secret Tax", "taxRate": 19}},
+		{Entity: "tax", Payload: map[string]interface{}{"id": "b", "name": "Costs $5", "taxRate": 9}},
+		{Entity: "tax", Payload: map[string]interface{}{"id": "c", "name": "New", "taxRate": 0}},
+	})
+
+	assert.NoError(t, writeEntityFile(file))
+
+	content, err := os.ReadFile(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `# the standard tax
+entity: tax
+payload:
+  id: a
+  name: ${TAX_NAME} # from the environment
+  taxRate: 19
+---
+entity: tax
+payload:
+  id: b
+  name: Costs $5
+  taxRate: 9
+---
+entity: tax
+payload:
+  id: c
+  name: New
+  taxRate: 0
+`, string(content))
+
+	t.Run("new entries are added to the last list", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte("- entity: tax\n  payload:\n    id: a\n"), os.ModePerm))
+
+		file, err := readEntityFile(path)
+		assert.NoError(t, err)
+
+		file.Entities = mergePulledEntities(file.Entities, []shop.EntitySync{{Entity: "tax", Payload: map[string]interface{}{"id": "b"}}})
+
+		assert.NoError(t, writeEntityFile(file))
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "- entity: tax\n  payload:\n    id: a\n- entity: tax\n  payload:\n    id: b\n", string(content))
+	})
+}
+
+func TestEntityPullTarget(t *testing.T) {
+	dir, ext := entityPullTarget("project", []string{"config/entities/**/*.yaml"})
+
+	assert.Equal(t, filepath.Join("project", "config", "entities"), dir)
+	assert.Equal(t, ".yaml", ext)
+}
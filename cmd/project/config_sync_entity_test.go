@@ -0,0 +1,199 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+func TestCleanPulledEntity(t *testing.T) {
+	record := map[string]interface{}{
+		"id":              "a",
+		"versionId":       "v",
+		"parentVersionId": "v",
+		"name":            "Tax",
+		"taxRate":         19.0,
+		"position":        nil,
+		"createdAt":       "2024-01-01",
+		"updatedAt":       nil,
+		"apiAlias":        "tax",
+		"translated":      map[string]interface{}{"name": "Tax"},
+		"products":        []interface{}{},
+		"breadcrumb":      []interface{}{"Tax"},
+	}
+
+	schema := entitySchema{Properties: map[string]entitySchemaField{
+		"products":   {Type: "association"},
+		"breadcrumb": {Type: "json_object", Flags: map[string]interface{}{"write_protected": []interface{}{}}},
+		"taxRate":    {Type: "float"},
+	}}
+
+	t.Run("writable fields", func(t *testing.T) {
+		assert.Equal(t, map[string]interface{}{"id": "a", "name": "Tax", "taxRate": 19.0}, cleanPulledEntity(record, schema, nil))
+	})
+
+	t.Run("included fields", func(t *testing.T) {
+		assert.Equal(t, map[string]interface{}{"id": "a", "name": "Tax", "createdAt": "2024-01-01"}, cleanPulledEntity(record, schema, []string{"name", "createdAt", "unknown"}))
+	})
+}
+
+func TestEntityExistsFilter(t *testing.T) {
+	record := map[string]interface{}{"id": "a", "name": "Tax"}
+
+	assert.Equal(t, &[]shop.EntitySyncFilter{{Type: "equals", Field: "id", Value: "a"}}, entityExistsFilter(record, nil))
+	assert.Equal(t, &[]shop.EntitySyncFilter{{Type: "equals", Field: "name", Value: "Tax"}}, entityExistsFilter(record, []string{"name"}))
+}
+
+func TestMergePulledEntities(t *testing.T) {
+	existing := []shop.EntitySync{
+		{Entity: "tax", Payload: map[string]interface{}{"id": "a", "name": "Old"}},
+		{Entity: "tax", Payload: map[string]interface{}{"name": "Without id"}},
+		{Entity: "currency", Payload: map[string]interface{}{"id": "b"}},
+	}
+
+	pulled := []shop.EntitySync{
+		{Entity: "tax", Payload: map[string]interface{}{"id": "a", "name": "New"}},
+		{Entity: "tax", Payload: map[string]interface{}{"id": "b", "name": "Other"}},
+	}
+
+	merged := mergePulledEntities(existing, pulled)
+
+	assert.Len(t, merged, 4)
+	assert.Equal(t, "New", merged[0].Payload["name"])
+	assert.Equal(t, "Without id", merged[1].Payload["name"])
+	assert.Equal(t, "currency", merged[2].Entity)
+	assert.Equal(t, "Other", merged[3].Payload["name"])
+}
+
+// newTestAdminClient returns a client for a fake shop, which records the decoded request bodies by path.
+func newTestAdminClient(t *testing.T, responses map[string]interface{}) (*adminSdk.Client, map[string]map[string]interface{}) {
+	t.Helper()
+
+	requests := map[string]map[string]interface{}{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == "/api/oauth/token" {
+			_, _ = w.Write([]byte(`{"access_token":"token","token_type":"Bearer","expires_in":3600}`))
+
+			return
+		}
+
+		body := map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests[r.URL.Path] = body
+
+		assert.NoError(t, json.NewEncoder(w).Encode(responses[r.URL.Path]))
+	}))
+
+	t.Cleanup(server.Close)
+
+	client, err := adminSdk.NewApiClient(context.Background(), server.URL, adminSdk.NewIntegrationCredentials("id", "secret", []string{"write"}), server.Client())
+	assert.NoError(t, err)
+
+	return client, requests
+}
+
+func TestEntitySearchSendsCriteria(t *testing.T) {
+	client, requests := newTestAdminClient(t, map[string]interface{}{
+		"/api/search-ids/tax": map[string]interface{}{"total": 0, "data": []string{}},
+		"/api/search/tax":     map[string]interface{}{"data": []map[string]interface{}{{"id": "tax-id", "name": "Tax", "taxRate": 19}}},
+	})
+
+	ctx := adminSdk.NewApiContext(context.Background())
+
+	record, err := fetchEntity(ctx, client, "tax", "tax-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "Tax", record["name"])
+	assert.Equal(t, map[string]interface{}{"ids": []interface{}{"tax-id"}}, requests["/api/search/tax"])
+
+	cfg := &shop.Config{Sync: &shop.ConfigSync{Entity: []shop.EntitySync{{
+		Entity:  "tax",
+		Exists:  &[]shop.EntitySyncFilter{{Type: "equals", Field: "name", Value: "Tax"}},
+		Payload: map[string]interface{}{"name": "Tax", "taxRate": 19},
+	}}}}
+
+	operation := NewConfigSyncOperation()
+
+	assert.NoError(t, EntitySync{}.Push(ctx, client, cfg, operation))
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "equals", "field": "name", "value": "Tax"}}, requests["/api/search-ids/tax"]["filter"])
+	assert.Len(t, operation.Operations, 1)
+}
+
+func TestSearchEntitiesSendsCriteria(t *testing.T) {
+	client, requests := newTestAdminClient(t, map[string]interface{}{
+		"/api/search/tax": map[string]interface{}{"data": []map[string]interface{}{{"id": "tax-id", "name": "Tax"}}},
+	})
+
+	records, err := searchEntities(adminSdk.NewApiContext(context.Background()), client, shop.EntityPullRule{
+		Entity: "tax",
+		Filter: &[]shop.EntitySyncFilter{{Type: "equals", Field: "name", Value: "Tax"}},
+		Fields: []string{"name"},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	criteria := requests["/api/search/tax"]
+	assert.Equal(t, float64(1), criteria["page"])
+	assert.Equal(t, float64(entityPullPageSize), criteria["limit"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "equals", "field": "name", "value": "Tax"}}, criteria["filter"])
+	assert.Equal(t, map[string]interface{}{"tax": []interface{}{"id", "name"}}, criteria["includes"])
+}
+
+func TestHasEntityIdentity(t *testing.T) {
+	assert.True(t, hasEntityIdentity(shop.EntitySync{Payload: map[string]interface{}{"id": "a"}}))
+	assert.True(t, hasEntityIdentity(shop.EntitySync{Exists: &[]shop.EntitySyncFilter{{Type: "equals", Field: "name", Value: "Tax"}}}))
+	assert.False(t, hasEntityIdentity(shop.EntitySync{Payload: map[string]interface{}{"name": "Tax"}}))
+	assert.False(t, hasEntityIdentity(shop.EntitySync{Exists: &[]shop.EntitySyncFilter{}}))
+}
+
+func TestPruneEntityGroupSendsScope(t *testing.T) {
+	client, requests := newTestAdminClient(t, map[string]interface{}{
+		"/api/search-ids/tax": map[string]interface{}{"total": 2, "data": []string{"kept", "removed"}},
+		"/api/search/tax":     map[string]interface{}{"data": []map[string]interface{}{{"id": "removed", "name": "Custom Old"}}},
+	})
+
+	group := shop.EntitySyncGroup{
+		Name:    "taxes",
+		Entity:  "tax",
+		Managed: true,
+		Scope:   &[]shop.EntitySyncFilter{{Type: "prefix", Field: "name", Value: "Custom "}},
+	}
+
+	operation := NewConfigSyncOperation()
+
+	assert.NoError(t, pruneEntityGroup(adminSdk.NewApiContext(context.Background()), client, group, map[string]bool{"kept": true}, operation))
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "prefix", "field": "name", "value": "Custom "}}, requests["/api/search-ids/tax"]["filter"])
+	assert.Len(t, operation.Operations, 1)
+
+	for _, op := range operation.Operations {
+		assert.Equal(t, "delete", op.Action)
+		assert.Equal(t, []map[string]interface{}{{"id": "removed"}}, op.Payload)
+	}
+}
+
+func TestResolveEntitySyncSendsLookupFilter(t *testing.T) {
+	client, requests := newTestAdminClient(t, map[string]interface{}{
+		"/api/search-ids/currency": map[string]interface{}{"total": 1, "data": []string{"currency-id"}},
+	})
+
+	entity, err := resolveEntitySync(adminSdk.NewApiContext(context.Background()), client, shop.EntitySync{
+		Entity: "shipping_method_price",
+		Payload: map[string]interface{}{
+			"currencyId": map[string]interface{}{"lookup": map[string]interface{}{"entity": "currency", "field": "isoCode", "value": "EUR"}},
+		},
+	}, NewConfigSyncOperation())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "currency-id", entity.Payload["currencyId"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "equals", "field": "isoCode", "value": "EUR"}}, requests["/api/search-ids/currency"]["filter"])
+}
@@ -0,0 +1,52 @@
+package project
+
+import (
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+var flowDefinition = treeDefinition{
+	Entity:      "flow",
+	Fields:      []string{"id", "name", "eventName", "priority", "active", "description", "customFields"},
+	Identifier:  []string{"eventName", "name"},
+	ChildField:  "sequences",
+	ChildEntity: "flow_sequence",
+	ChildFields: []string{"id", "trueCase", "position", "displayGroup", "ruleId", "actionName", "config", "customFields"},
+	NestedField: "sequences",
+	Phase:       syncPhaseFlow,
+	Directory:   ".shopware-cli/flow",
+}
+
+type FlowSync struct{}
+
+func (FlowSync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, config *shop.Config, operation *ConfigSyncOperation) error {
+	files := make([]string, 0, len(config.Sync.Flow))
+
+	for _, flow := range config.Sync.Flow {
+		files = append(files, flow.File)
+	}
+
+	return pushTrees(ctx, client, flowDefinition, files, operation)
+}
+
+func (FlowSync) Pull(ctx adminSdk.ApiContext, client *adminSdk.Client, config *shop.Config) error {
+	existing := make([]string, 0, len(config.Sync.Flow))
+
+	for _, flow := range config.Sync.Flow {
+		existing = append(existing, flow.File)
+	}
+
+	files, err := pullTrees(ctx, client, flowDefinition, existing)
+	if err != nil {
+		return err
+	}
+
+	config.Sync.Flow = make([]shop.FlowDefinition, 0, len(files))
+
+	for _, file := range files {
+		config.Sync.Flow = append(config.Sync.Flow, shop.FlowDefinition{File: file})
+	}
+
+	return nil
+}
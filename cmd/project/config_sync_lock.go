@@ -0,0 +1,204 @@
+package project
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/manifoldco/promptui"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+)
+
+const (
+	configLockFile = ".shopware-project.lock"
+
+	configConflictKeepLocal  = "local"
+	configConflictKeepRemote = "remote"
+	configConflictPull       = "pull"
+)
+
+// configStateKey identifies a field managed by the config, in the same way as the changes.
+type configStateKey struct {
+	Applier string
+	Target  string
+	Field   string
+}
+
+// ConfigLock holds the hashes of the remote values of all managed fields after the last push, by the URL of the shop.
+type ConfigLock struct {
+	Shops map[string]*ConfigLockShop `json:"shops"`
+}
+
+type ConfigLockShop struct {
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Hashes are the sha256 hashes of the remote values by applier, target and field
+	Hashes map[string]map[string]map[string]string `json:"hashes"`
+}
+
+func configLockPath() string {
+	return filepath.Join(filepath.Dir(projectConfigPath), configLockFile)
+}
+
+// readConfigLock reads the lock file next to the project config. The lock is empty, when nothing has been pushed yet.
+func readConfigLock() (*ConfigLock, error) {
+	lock := &ConfigLock{Shops: map[string]*ConfigLockShop{}}
+
+	content, err := os.ReadFile(configLockPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read lock file: %w", err)
+	}
+
+	if err := json.Unmarshal(content, lock); err != nil {
+		return nil, fmt.Errorf("cannot read lock file %s: %w", configLockPath(), err)
+	}
+
+	if lock.Shops == nil {
+		lock.Shops = map[string]*ConfigLockShop{}
+	}
+
+	return lock, nil
+}
+
+func writeConfigLock(lock *ConfigLock) error {
+	content, err := json.MarshalIndent(lock, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(configLockPath(), append(content, '\n'), os.ModePerm)
+}
+
+// hash returns the locked hash of the field.
+func (s *ConfigLockShop) hash(key configStateKey) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+
+	hash, ok := s.Hashes[key.Applier][key.Target][key.Field]
+
+	return hash, ok
+}
+
+func (s *ConfigLockShop) setHash(key configStateKey, hash string) {
+	if _, ok := s.Hashes[key.Applier]; !ok {
+		s.Hashes[key.Applier] = map[string]map[string]string{}
+	}
+
+	if _, ok := s.Hashes[key.Applier][key.Target]; !ok {
+		s.Hashes[key.Applier][key.Target] = map[string]string{}
+	}
+
+	s.Hashes[key.Applier][key.Target][key.Field] = hash
+}
+
+// update replaces the hashes of the shop with the remote values tracked by the operation. The fields, which kept the remote value, keep their previous hash, so the conflict is reported again until the local config is updated.
+func (l *ConfigLock) update(url string, operation *ConfigSyncOperation, keep map[configStateKey]bool) {
+	previous := l.Shops[url]
+
+	shop := &ConfigLockShop{
+		UpdatedAt: time.Now().UTC(),
+		Hashes:    map[string]map[string]map[string]string{},
+	}
+
+	for key, value := range operation.remote {
+		hash := hashRemoteValue(value)
+
+		if locked, ok := previous.hash(key); ok && keep[key] {
+			hash = locked
+		}
+
+		shop.setHash(key, hash)
+	}
+
+	l.Shops[url] = shop
+}
+
+// markConfigConflicts marks the changes, whose remote value differs from the value locked by the last push, and returns them.
+func markConfigConflicts(lock *ConfigLock, url string, operation *ConfigSyncOperation) []ConfigChange {
+	conflicts := make([]ConfigChange, 0)
+
+	shop, ok := lock.Shops[url]
+	if !ok {
+		return conflicts
+	}
+
+	for i, change := range operation.Changes {
+		key := configStateKey{Applier: change.Applier, Target: change.Target, Field: change.Field}
+
+		remote, tracked := operation.remote[key]
+		if !tracked {
+			continue
+		}
+
+		if locked, ok := shop.hash(key); ok && locked != hashRemoteValue(remote) {
+			operation.Changes[i].Conflict = true
+			conflicts = append(conflicts, operation.Changes[i])
+		}
+	}
+
+	return conflicts
+}
+
+// hashRemoteValue hashes the JSON encoding of the value, the keys of maps are sorted by the encoder.
+func hashRemoteValue(value interface{}) string {
+	content, _ := json.Marshal(value)
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// resolveConfigConflicts lists the conflicts and returns how they are resolved. Without a resolution given by flag, the user is asked.
+func resolveConfigConflicts(ctx context.Context, conflicts []ConfigChange, resolution string, autoApprove bool) (string, error) {
+	logging.FromContext(ctx).Warnf("Following fields have been changed in the shop since the last push")
+
+	for _, conflict := range conflicts {
+		logging.FromContext(ctx).Warnf("%s %s: %s", conflict.Applier, conflict.Target, conflict.Field)
+	}
+
+	if resolution == "" {
+		if autoApprove {
+			return "", fmt.Errorf("%d fields have been changed in the shop since the last push, choose with --conflict whether to keep the %s or the %s values", len(conflicts), configConflictKeepLocal, configConflictKeepRemote)
+		}
+
+		p := promptui.Select{
+			Label: "How do you want to resolve the conflicts?",
+			Items: []string{
+				"Keep local: overwrite the changes made in the shop",
+				"Keep remote: skip these fields in this push",
+				"Pull: abort and update the local config first",
+			},
+		}
+
+		index, _, err := p.Run()
+		if err != nil {
+			return "", err
+		}
+
+		resolution = []string{configConflictKeepLocal, configConflictKeepRemote, configConflictPull}[index]
+	}
+
+	if err := validateConfigConflictResolution(resolution); err != nil {
+		return "", err
+	}
+
+	return resolution, nil
+}
+
+func validateConfigConflictResolution(resolution string) error {
+	if resolution != configConflictKeepLocal && resolution != configConflictKeepRemote && resolution != configConflictPull {
+		return fmt.Errorf("unsupported conflict resolution %q, supported are %s, %s and %s", resolution, configConflictKeepLocal, configConflictKeepRemote, configConflictPull)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package project
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigLockConflicts(t *testing.T) {
+	previous := projectConfigPath
+	projectConfigPath = filepath.Join(t.TempDir(), ".shopware-project.yml")
+
+	defer func() {
+		projectConfigPath = previous
+	}()
+
+	lock, err := readConfigLock()
+	assert.NoError(t, err)
+	assert.Empty(t, lock.Shops)
+
+	pushed := NewConfigSyncOperation()
+	pushed.TrackRemote("system_config", "default", "core.basicInformation.shopName", "Demo")
+	pushed.TrackRemote("system_config", "default", "core.basicInformation.email", nil)
+	pushed.TrackRemote("theme", "Storefront", "sw-color-brand-primary", "#008490")
+
+	lock.update("http://localhost", pushed, nil)
+	assert.NoError(t, writeConfigLock(lock))
+
+	lock, err = readConfigLock()
+	assert.NoError(t, err)
+	assert.Equal(t, hashRemoteValue("Demo"), lock.Shops["http://localhost"].Hashes["system_config"]["default"]["core.basicInformation.shopName"])
+
+	operation := NewConfigSyncOperation()
+	// changed in the shop and locally
+	operation.TrackRemote("system_config", "default", "core.basicInformation.shopName", "Changed in admin")
+	operation.AddChange("system_config", "default", "core.basicInformation.shopName", "Changed in admin", "My Shop")
+	// only changed locally
+	operation.TrackRemote("theme", "Storefront", "sw-color-brand-primary", "#008490")
+	operation.AddChange("theme", "Storefront", "sw-color-brand-primary", "#008490", "#ff0000")
+	// not locked yet
+	operation.TrackRemote("snippet", "BASE en-GB", "checkout.cartHeader", "Cart")
+	operation.AddChange("snippet", "BASE en-GB", "checkout.cartHeader", "Cart", "Basket")
+
+	assert.Empty(t, markConfigConflicts(lock, "http://other-shop", operation))
+
+	conflicts := markConfigConflicts(lock, "http://localhost", operation)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, "core.basicInformation.shopName", conflicts[0].Field)
+	assert.True(t, operation.Changes[0].Conflict)
+	assert.False(t, operation.Changes[1].Conflict)
+
+	t.Run("kept remote values keep the locked hash", func(t *testing.T) {
+		keep := map[configStateKey]bool{{Applier: "system_config", Target: "default", Field: "core.basicInformation.shopName"}: true}
+
+		lock.update("http://localhost", operation, keep)
+
+		hashes := lock.Shops["http://localhost"].Hashes
+		assert.Equal(t, hashRemoteValue("Demo"), hashes["system_config"]["default"]["core.basicInformation.shopName"])
+		assert.Equal(t, hashRemoteValue("Cart"), hashes["snippet"]["BASE en-GB"]["checkout.cartHeader"])
+		assert.NotContains(t, hashes["system_config"]["default"], "core.basicInformation.email")
+	})
+}
+
+func TestResolveConfigConflicts(t *testing.T) {
+	conflicts := []ConfigChange{{Applier: "system_config", Target: "default", Field: "core.basicInformation.shopName", Conflict: true}}
+
+	_, err := resolveConfigConflicts(context.Background(), conflicts, "", true)
+	assert.ErrorContains(t, err, "--conflict")
+
+	resolution, err := resolveConfigConflicts(context.Background(), conflicts, configConflictKeepRemote, true)
+	assert.NoError(t, err)
+	assert.Equal(t, configConflictKeepRemote, resolution)
+
+	_, err = resolveConfigConflicts(context.Background(), conflicts, "theirs", false)
+	assert.ErrorContains(t, err, "unsupported conflict resolution")
+}
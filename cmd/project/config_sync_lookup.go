@@ -0,0 +1,181 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+const entityLookupKey = "lookup"
+
+// entityLookupSearch returns the ids of the entity matching the filter.
+type entityLookupSearch func(entity string, filter *[]shop.EntitySyncFilter) ([]string, error)
+
+// newEntityLookupSearch searches the shop and falls back to the records upserted earlier in this run.
+func newEntityLookupSearch(ctx adminSdk.ApiContext, client *adminSdk.Client, operation *ConfigSyncOperation) entityLookupSearch {
+	return func(entity string, filter *[]shop.EntitySyncFilter) ([]string, error) {
+		res, err := searchEntityIds(ctx, client, entity, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(res.Data) == 0 {
+			return pendingEntityIds(operation.Operations, entity, *filter), nil
+		}
+
+		return res.Data, nil
+	}
+}
+
+type entityLookup struct {
+	Entity string
+	Field  string
+	Value  interface{}
+}
+
+func (l entityLookup) cacheKey() string {
+	return fmt.Sprintf("%s|%s|%v", l.Entity, l.Field, l.Value)
+}
+
+// parseEntityLookup detects a lookup like `{lookup: {entity: currency, field: isoCode, value: EUR}}`.
+func parseEntityLookup(value interface{}) (*entityLookup, bool, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return nil, false, nil
+	}
+
+	raw, ok := m[entityLookupKey]
+	if !ok {
+		return nil, false, nil
+	}
+
+	spec, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, true, fmt.Errorf("lookup must be a map with entity, field and value")
+	}
+
+	lookup := entityLookup{Value: spec["value"]}
+	lookup.Entity, _ = spec["entity"].(string)
+	lookup.Field, _ = spec["field"].(string)
+
+	if lookup.Entity == "" || lookup.Field == "" || lookup.Value == nil {
+		return nil, true, fmt.Errorf("lookup requires entity, field and value")
+	}
+
+	return &lookup, true, nil
+}
+
+// resolveEntityLookups replaces all lookups in the value with the id of the matching record. The value is copied, resolved ids are stored in the cache.
+func resolveEntityLookups(value interface{}, cache map[string]string, search entityLookupSearch) (interface{}, error) {
+	lookup, isLookup, err := parseEntityLookup(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if isLookup {
+		return resolveEntityLookup(*lookup, cache, search)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+
+		for key, item := range v {
+			r, err := resolveEntityLookups(item, cache, search)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+
+			resolved[key] = r
+		}
+
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+
+		for i, item := range v {
+			r, err := resolveEntityLookups(item, cache, search)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[i] = r
+		}
+
+		return resolved, nil
+	}
+
+	return value, nil
+}
+
+func resolveEntityLookup(lookup entityLookup, cache map[string]string, search entityLookupSearch) (string, error) {
+	key := lookup.cacheKey()
+
+	if id, ok := cache[key]; ok {
+		return id, nil
+	}
+
+	ids, err := search(lookup.Entity, &[]shop.EntitySyncFilter{{Type: "equals", Field: lookup.Field, Value: lookup.Value}})
+	if err != nil {
+		return "", err
+	}
+
+	if len(ids) == 0 {
+		return "", fmt.Errorf("lookup of %s with %s %v found no record", lookup.Entity, lookup.Field, lookup.Value)
+	}
+
+	if len(ids) > 1 {
+		return "", fmt.Errorf("lookup of %s with %s %v is ambiguous, found %d records", lookup.Entity, lookup.Field, lookup.Value, len(ids))
+	}
+
+	cache[key] = ids[0]
+
+	return ids[0], nil
+}
+
+// pendingEntityIds returns the ids of the records upserted earlier in this run which match the equals filters, so lookups can refer to records created in the same push.
+func pendingEntityIds(operations Operation, entity string, filter []shop.EntitySyncFilter) []string {
+	ids := make([]string, 0)
+
+	keys := make([]string, 0, len(operations))
+
+	for key := range operations {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		op := operations[key]
+
+		if op.Action != "upsert" || op.Entity != entity {
+			continue
+		}
+
+		records, _ := op.Payload.([]map[string]interface{})
+
+		for _, record := range records {
+			id, ok := record["id"].(string)
+			if !ok || !matchesEqualsFilter(record, filter) {
+				continue
+			}
+
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func matchesEqualsFilter(record map[string]interface{}, filter []shop.EntitySyncFilter) bool {
+	for _, f := range filter {
+		if f.Type != "equals" || fmt.Sprintf("%v", record[f.Field]) != fmt.Sprintf("%v", f.Value) {
+			return false
+		}
+	}
+
+	return true
+}
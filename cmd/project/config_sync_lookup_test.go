@@ -0,0 +1,83 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+func TestResolveEntityLookups(t *testing.T) {
+	currency := map[string]interface{}{"lookup": map[string]interface{}{"entity": "currency", "field": "isoCode", "value": "EUR"}}
+
+	t.Run("resolves nested lookups once", func(t *testing.T) {
+		searches := 0
+		search := func(entity string, filter *[]shop.EntitySyncFilter) ([]string, error) {
+			searches++
+
+			assert.Equal(t, "currency", entity)
+			assert.Equal(t, &[]shop.EntitySyncFilter{{Type: "equals", Field: "isoCode", Value: "EUR"}}, filter)
+
+			return []string{"eur-id"}, nil
+		}
+
+		payload := map[string]interface{}{
+			"name":       "Rule",
+			"currencyId": currency,
+			"prices":     []interface{}{map[string]interface{}{"currencyId": currency, "net": 1}},
+		}
+
+		resolved, err := resolveEntityLookups(payload, map[string]string{}, search)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"name":       "Rule",
+			"currencyId": "eur-id",
+			"prices":     []interface{}{map[string]interface{}{"currencyId": "eur-id", "net": 1}},
+		}, resolved)
+		assert.Equal(t, 1, searches)
+		assert.Equal(t, currency, payload["currencyId"])
+	})
+
+	t.Run("no record", func(t *testing.T) {
+		_, err := resolveEntityLookups(currency, map[string]string{}, func(string, *[]shop.EntitySyncFilter) ([]string, error) {
+			return []string{}, nil
+		})
+
+		assert.ErrorContains(t, err, "found no record")
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		_, err := resolveEntityLookups(currency, map[string]string{}, func(string, *[]shop.EntitySyncFilter) ([]string, error) {
+			return []string{"a", "b"}, nil
+		})
+
+		assert.ErrorContains(t, err, "ambiguous")
+	})
+
+	t.Run("invalid lookup", func(t *testing.T) {
+		_, err := resolveEntityLookups(map[string]interface{}{"lookup": map[string]interface{}{"entity": "currency"}}, map[string]string{}, nil)
+
+		assert.ErrorContains(t, err, "requires entity, field and value")
+	})
+
+	t.Run("lookup key next to other fields is kept", func(t *testing.T) {
+		value := map[string]interface{}{"lookup": "x", "name": "y"}
+
+		resolved, err := resolveEntityLookups(value, map[string]string{}, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, value, resolved)
+	})
+}
+
+func TestPendingEntityIds(t *testing.T) {
+	operations := Operation{
+		"00000-upsert-currency": {Action: "upsert", Entity: "currency", Payload: []map[string]interface{}{{"id": "chf", "isoCode": "CHF"}}},
+		"00001-upsert-tax":      {Action: "upsert", Entity: "tax", Payload: []map[string]interface{}{{"id": "tax", "isoCode": "CHF"}}},
+	}
+
+	assert.Equal(t, []string{"chf"}, pendingEntityIds(operations, "currency", []shop.EntitySyncFilter{{Type: "equals", Field: "isoCode", Value: "CHF"}}))
+	assert.Empty(t, pendingEntityIds(operations, "currency", []shop.EntitySyncFilter{{Type: "equals", Field: "isoCode", Value: "USD"}}))
+}
@@ -38,19 +38,29 @@ func (MailTemplateSync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, c
 					for _, configTranslation := range configEntry.Translations {
 						if translation.Language.Name == configTranslation.Language {
 							translationUpdate := make(map[string]interface{})
+							target := fmt.Sprintf("%s (%s)", mailTemplateName(external), configTranslation.Language)
 
-							if translation.SenderName != configTranslation.SenderName {
+							operation.TrackRemote(shop.SyncOptionMailTemplate, target, "senderName", translation.SenderName)
+							operation.TrackRemote(shop.SyncOptionMailTemplate, target, "subject", translation.Subject)
+							operation.TrackRemote(shop.SyncOptionMailTemplate, target, "customFields", translation.CustomFields)
+
+							if translation.SenderName != configTranslation.SenderName && !operation.KeepsRemote(shop.SyncOptionMailTemplate, target, "senderName") {
 								translationUpdate["senderName"] = configTranslation.SenderName
+								operation.AddChange(shop.SyncOptionMailTemplate, target, "senderName", translation.SenderName, configTranslation.SenderName)
 							}
 
-							if translation.Subject != configTranslation.Subject {
+							if translation.Subject != configTranslation.Subject && !operation.KeepsRemote(shop.SyncOptionMailTemplate, target, "subject") {
 								translationUpdate["subject"] = configTranslation.Subject
+								operation.AddChange(shop.SyncOptionMailTemplate, target, "subject", translation.Subject, configTranslation.Subject)
 							}
 
 							if configTranslation.HTML != "" {
 								if content, err := os.ReadFile(configTranslation.HTML); err == nil {
-									if translation.ContentHtml != string(content) {
+									operation.TrackRemote(shop.SyncOptionMailTemplate, target, "contentHtml", translation.ContentHtml)
+
+									if translation.ContentHtml != string(content) && !operation.KeepsRemote(shop.SyncOptionMailTemplate, target, "contentHtml") {
 										translationUpdate["contentHtml"] = string(content)
+										operation.AddChange(shop.SyncOptionMailTemplate, target, "contentHtml", translation.ContentHtml, string(content))
 									}
 								} else {
 									logging.FromContext(ctx.Context).Errorf("Cannot read file %s, with error: %s", configTranslation.HTML, err)
@@ -59,8 +69,11 @@ func (MailTemplateSync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, c
 
 							if configTranslation.Plain != "" {
 								if content, err := os.ReadFile(configTranslation.Plain); err == nil {
-									if translation.ContentPlain != string(content) {
+									operation.TrackRemote(shop.SyncOptionMailTemplate, target, "contentPlain", translation.ContentPlain)
+
+									if translation.ContentPlain != string(content) && !operation.KeepsRemote(shop.SyncOptionMailTemplate, target, "contentPlain") {
 										translationUpdate["contentPlain"] = string(content)
+										operation.AddChange(shop.SyncOptionMailTemplate, target, "contentPlain", translation.ContentPlain, string(content))
 									}
 								} else {
 									logging.FromContext(ctx.Context).Errorf("Cannot read file %s, with error: %s", configTranslation.Plain, err)
@@ -70,8 +83,9 @@ func (MailTemplateSync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, c
 							localCustomFields, _ := json.Marshal(configTranslation.CustomFields)
 							remoteCustomFields, _ := json.Marshal(translation.CustomFields)
 
-							if !bytes.Equal(localCustomFields, remoteCustomFields) {
+							if !bytes.Equal(localCustomFields, remoteCustomFields) && !operation.KeepsRemote(shop.SyncOptionMailTemplate, target, "customFields") {
 								translationUpdate["customFields"] = configTranslation.CustomFields
+								operation.AddChange(shop.SyncOptionMailTemplate, target, "customFields", translation.CustomFields, configTranslation.CustomFields)
 							}
 
 							if len(translationUpdate) > 0 {
@@ -93,11 +107,11 @@ func (MailTemplateSync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, c
 	}
 
 	if len(mailUpdates) > 0 {
-		operation.Operations["update-mail-template"] = adminSdk.SyncOperation{
+		operation.AddOperation(syncPhaseMailTemplate, adminSdk.SyncOperation{
 			Action:  "upsert",
 			Entity:  "mail_template",
 			Payload: mailUpdates,
-		}
+		})
 	}
 
 	return nil
@@ -173,6 +187,14 @@ func (MailTemplateSync) Pull(ctx adminSdk.ApiContext, client *adminSdk.Client, c
 	return nil
 }
 
+func mailTemplateName(mailTemplate adminSdk.MailTemplate) string {
+	if mailTemplate.MailTemplateType != nil && mailTemplate.MailTemplateType.TechnicalName != "" {
+		return mailTemplate.MailTemplateType.TechnicalName
+	}
+
+	return mailTemplate.Id
+}
+
 func getDuplicateMailTemplateTypes(data []adminSdk.MailTemplate) map[string]bool {
 	check := make(map[string]bool)
 	duplicates := make(map[string]bool)
@@ -0,0 +1,211 @@
+package project
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+)
+
+const (
+	mediaReferenceKey  = "media"
+	mediaChangeApplier = "media"
+)
+
+// MediaUpload is a local file which is uploaded as media before the sync operations are applied.
+type MediaUpload struct {
+	Id        string
+	Path      string
+	FileName  string
+	Extension string
+}
+
+// parseMediaReference detects a media reference like `{media: ./assets/logo.svg}`.
+func parseMediaReference(value interface{}) (string, bool) {
+	m, ok := value.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+
+	path, ok := m[mediaReferenceKey].(string)
+	if !ok || path == "" {
+		return "", false
+	}
+
+	return path, true
+}
+
+// resolveMediaReferences replaces all media references in the value with the id of the media. The value is copied.
+func resolveMediaReferences(ctx adminSdk.ApiContext, client *adminSdk.Client, value interface{}, operation *ConfigSyncOperation) (interface{}, error) {
+	if path, ok := parseMediaReference(value); ok {
+		return resolveMediaReference(ctx, client, path, operation)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+
+		for key, item := range v {
+			r, err := resolveMediaReferences(ctx, client, item, operation)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[key] = r
+		}
+
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+
+		for i, item := range v {
+			r, err := resolveMediaReferences(ctx, client, item, operation)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[i] = r
+		}
+
+		return resolved, nil
+	}
+
+	return value, nil
+}
+
+// resolveMediaReference returns the media id of the file. The id is derived from the content, so a file is only uploaded when the shop does not have this content yet.
+func resolveMediaReference(ctx adminSdk.ApiContext, client *adminSdk.Client, path string, operation *ConfigSyncOperation) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(projectConfigPath), path)
+	}
+
+	if id, ok := operation.media[path]; ok {
+		return id, nil
+	}
+
+	upload, err := newMediaUpload(path)
+	if err != nil {
+		return "", err
+	}
+
+	// copies of a file have the same id, they are only uploaded once
+	for _, id := range operation.media {
+		if id == upload.Id {
+			operation.media[path] = upload.Id
+
+			return upload.Id, nil
+		}
+	}
+
+	record, err := fetchEntity(ctx, client, "media", upload.Id)
+	if err != nil {
+		return "", err
+	}
+
+	if hasFile, _ := record["hasFile"].(bool); !hasFile {
+		operation.MediaUploads = append(operation.MediaUploads, *upload)
+		operation.AddChange(mediaChangeApplier, path, "upload", nil, upload.FileName+"."+upload.Extension)
+	}
+
+	operation.media[path] = upload.Id
+
+	return upload.Id, nil
+}
+
+func newMediaUpload(path string) (*MediaUpload, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read media %s: %w", path, err)
+	}
+
+	hash := sha256.Sum256(content)
+	hexHash := hex.EncodeToString(hash[:])
+
+	extension := strings.TrimPrefix(filepath.Ext(path), ".")
+	if extension == "" {
+		return nil, fmt.Errorf("media %s needs a file extension", path)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	return &MediaUpload{
+		Id:        hexHash[:32],
+		Path:      path,
+		FileName:  fmt.Sprintf("%s-%s", name, hexHash[:8]),
+		Extension: extension,
+	}, nil
+}
+
+// uploadMedia creates the media entities and uploads their files.
+func uploadMedia(ctx adminSdk.ApiContext, client *adminSdk.Client, uploads []MediaUpload) error {
+	if len(uploads) == 0 {
+		return nil
+	}
+
+	payload := make([]map[string]interface{}, 0, len(uploads))
+
+	for _, upload := range uploads {
+		payload = append(payload, map[string]interface{}{"id": upload.Id})
+	}
+
+	if _, err := client.Bulk.Sync(ctx, map[string]adminSdk.SyncOperation{"create-media": {
+		Action:  "upsert",
+		Entity:  "media",
+		Payload: payload,
+	}}); err != nil {
+		return err
+	}
+
+	for _, upload := range uploads {
+		content, err := os.ReadFile(upload.Path)
+		if err != nil {
+			return err
+		}
+
+		query := url.Values{}
+		query.Set("extension", upload.Extension)
+		query.Set("fileName", upload.FileName)
+
+		r, err := client.NewRawRequest(ctx, "POST", fmt.Sprintf("/api/_action/media/%s/upload?%s", upload.Id, query.Encode()), bytes.NewReader(content))
+		if err != nil {
+			return err
+		}
+
+		contentType := mime.TypeByExtension("." + upload.Extension)
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		r.Header.Set("Content-Type", contentType)
+
+		if _, err := client.Do(ctx.Context, r, nil); err != nil {
+			return fmt.Errorf("cannot upload media %s: %w", upload.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// resolveThemeMedia replaces media references in the theme settings.
+func resolveThemeMedia(ctx adminSdk.ApiContext, client *adminSdk.Client, settings map[string]adminSdk.ThemeConfigValue, operation *ConfigSyncOperation) (map[string]adminSdk.ThemeConfigValue, error) {
+	resolved := make(map[string]adminSdk.ThemeConfigValue, len(settings))
+
+	for name, setting := range settings {
+		value, err := resolveMediaReferences(ctx, client, setting.Value, operation)
+		if err != nil {
+			return nil, fmt.Errorf("theme setting %s: %w", name, err)
+		}
+
+		setting.Value = value
+		resolved[name] = setting
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,88 @@
+package project
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMediaReference(t *testing.T) {
+	path, ok := parseMediaReference(map[string]interface{}{"media": "./assets/logo.svg"})
+	assert.True(t, ok)
+	assert.Equal(t, "./assets/logo.svg", path)
+
+	_, ok = parseMediaReference(map[string]interface{}{"media": map[string]interface{}{"source": "static", "value": "id"}})
+	assert.False(t, ok)
+
+	_, ok = parseMediaReference(map[string]interface{}{"media": "./logo.svg", "other": true})
+	assert.False(t, ok)
+}
+
+func TestNewMediaUpload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "logo.svg")
+
+	assert.NoError(t, os.WriteFile(file, []byte("<svg/>"), os.ModePerm))
+
+	upload, err := newMediaUpload(file)
+	assert.NoError(t, err)
+	assert.Len(t, upload.Id, 32)
+	assert.Equal(t, "svg", upload.Extension)
+	assert.Equal(t, "logo-"+upload.Id[:8], upload.FileName)
+
+	same := filepath.Join(dir, "copy.svg")
+	assert.NoError(t, os.WriteFile(same, []byte("<svg/>"), os.ModePerm))
+
+	copied, err := newMediaUpload(same)
+	assert.NoError(t, err)
+	assert.Equal(t, upload.Id, copied.Id)
+
+	t.Run("without extension", func(t *testing.T) {
+		noExtension := filepath.Join(dir, "logo")
+		assert.NoError(t, os.WriteFile(noExtension, []byte("<svg/>"), os.ModePerm))
+
+		_, err := newMediaUpload(noExtension)
+		assert.ErrorContains(t, err, "needs a file extension")
+	})
+}
+
+func TestResolveMediaReferencesUsesCache(t *testing.T) {
+	operation := NewConfigSyncOperation()
+	operation.media[filepath.Join(filepath.Dir(projectConfigPath), "assets/logo.svg")] = "logo-id"
+
+	resolved, err := resolveMediaReferences(adminSdk.NewApiContext(context.Background()), nil, map[string]interface{}{
+		"name":    "Logo",
+		"logos":   []interface{}{map[string]interface{}{"media": "assets/logo.svg"}},
+		"mediaId": map[string]interface{}{"media": "assets/logo.svg"},
+	}, operation)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "Logo", "logos": []interface{}{"logo-id"}, "mediaId": "logo-id"}, resolved)
+}
+
+func TestResolveMediaReferenceDeduplicatesContent(t *testing.T) {
+	dir := t.TempDir()
+	logo := filepath.Join(dir, "logo.svg")
+	copied := filepath.Join(dir, "copy.svg")
+
+	assert.NoError(t, os.WriteFile(logo, []byte("<svg/>"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(copied, []byte("<svg/>"), os.ModePerm))
+
+	upload, err := newMediaUpload(logo)
+	assert.NoError(t, err)
+
+	operation := NewConfigSyncOperation()
+	operation.media[logo] = upload.Id
+	operation.MediaUploads = append(operation.MediaUploads, *upload)
+
+	id, err := resolveMediaReference(adminSdk.NewApiContext(context.Background()), nil, copied, operation)
+
+	assert.NoError(t, err)
+	assert.Equal(t, upload.Id, id)
+	assert.Len(t, operation.MediaUploads, 1)
+	assert.Equal(t, upload.Id, operation.media[copied])
+}
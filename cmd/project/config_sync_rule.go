@@ -0,0 +1,52 @@
+package project
+
+import (
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+var ruleDefinition = treeDefinition{
+	Entity:      "rule",
+	Fields:      []string{"id", "name", "priority", "description", "moduleTypes", "customFields"},
+	Identifier:  []string{"name"},
+	ChildField:  "conditions",
+	ChildEntity: "rule_condition",
+	ChildFields: []string{"id", "type", "position", "value", "customFields"},
+	NestedField: "children",
+	Phase:       syncPhaseRule,
+	Directory:   ".shopware-cli/rule",
+}
+
+type RuleSync struct{}
+
+func (RuleSync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, config *shop.Config, operation *ConfigSyncOperation) error {
+	files := make([]string, 0, len(config.Sync.Rule))
+
+	for _, rule := range config.Sync.Rule {
+		files = append(files, rule.File)
+	}
+
+	return pushTrees(ctx, client, ruleDefinition, files, operation)
+}
+
+func (RuleSync) Pull(ctx adminSdk.ApiContext, client *adminSdk.Client, config *shop.Config) error {
+	existing := make([]string, 0, len(config.Sync.Rule))
+
+	for _, rule := range config.Sync.Rule {
+		existing = append(existing, rule.File)
+	}
+
+	files, err := pullTrees(ctx, client, ruleDefinition, existing)
+	if err != nil {
+		return err
+	}
+
+	config.Sync.Rule = make([]shop.RuleDefinition, 0, len(files))
+
+	for _, file := range files {
+		config.Sync.Rule = append(config.Sync.Rule, shop.RuleDefinition{File: file})
+	}
+
+	return nil
+}
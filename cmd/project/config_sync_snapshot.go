@@ -0,0 +1,644 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+const (
+	configSnapshotDir = ".shopware-cli/snapshots"
+	// snapshotCascadeDepth limits how deep the records deleted together with a deleted record are loaded
+	snapshotCascadeDepth = 4
+)
+
+// ConfigSnapshot holds the remote values, which a push is about to change, to restore them with project config rollback.
+type ConfigSnapshot struct {
+	CreatedAt time.Time `json:"createdAt"`
+	URL       string    `json:"url"`
+	// Operations restore the changed entities and delete the created ones
+	Operations     Operation              `json:"operations"`
+	SystemSettings []SystemConfigSnapshot `json:"systemSettings"`
+	ThemeSettings  ThemeSettings          `json:"themeSettings"`
+	// Skipped describes the changes which cannot be restored
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+type SystemConfigSnapshot struct {
+	SalesChannel *string `json:"salesChannel"`
+	// Settings are the previous values, null removes a key which did not exist before
+	Settings map[string]interface{} `json:"settings"`
+}
+
+func (s ConfigSnapshot) HasChanges() bool {
+	return s.Operations.HasChanges() || len(s.SystemSettings) > 0 || s.ThemeSettings.HasChanges()
+}
+
+// createConfigSnapshot reads the remote values of everything the operation is about to change.
+func createConfigSnapshot(ctx adminSdk.ApiContext, client *adminSdk.Client, cfg *shop.Config, operation *ConfigSyncOperation) (*ConfigSnapshot, error) {
+	snapshot := &ConfigSnapshot{
+		CreatedAt:      time.Now(),
+		URL:            cfg.URL,
+		Operations:     Operation{},
+		SystemSettings: []SystemConfigSnapshot{},
+		ThemeSettings:  ThemeSettings{},
+	}
+
+	if err := snapshotEntities(ctx, client, operation.Operations, snapshot); err != nil {
+		return nil, err
+	}
+
+	for salesChannel, settings := range operation.SystemSettings {
+		if len(settings) == 0 {
+			continue
+		}
+
+		remote, err := readSystemConfig(ctx, client, salesChannel)
+		if err != nil {
+			return nil, err
+		}
+
+		previous := make(map[string]interface{}, len(settings))
+
+		for key := range settings {
+			previous[key] = nil
+		}
+
+		for _, record := range remote.Data {
+			if _, ok := previous[record.ConfigurationKey]; ok {
+				previous[record.ConfigurationKey] = record.ConfigurationValue
+			}
+		}
+
+		snapshot.SystemSettings = append(snapshot.SystemSettings, SystemConfigSnapshot{SalesChannel: salesChannel, Settings: previous})
+	}
+
+	for _, themeOp := range operation.ThemeSettings {
+		if len(themeOp.Settings) == 0 {
+			continue
+		}
+
+		remote, resp, err := client.ThemeManager.GetConfiguration(ctx, themeOp.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := resp.Body.Close(); err != nil {
+			return nil, err
+		}
+
+		previous := ThemeSyncOperation{Id: themeOp.Id, Name: themeOp.Name, Settings: map[string]adminSdk.ThemeConfigValue{}}
+
+		for name := range themeOp.Settings {
+			if remote.CurrentFields != nil {
+				previous.Settings[name] = (*remote.CurrentFields)[name]
+			}
+		}
+
+		snapshot.ThemeSettings = append(snapshot.ThemeSettings, previous)
+	}
+
+	if len(operation.MediaUploads) > 0 {
+		snapshot.Skipped = append(snapshot.Skipped, fmt.Sprintf("%d uploaded media files are kept", len(operation.MediaUploads)))
+	}
+
+	return snapshot, nil
+}
+
+// entitySnapshot collects the records to restore per entity.
+type entitySnapshot struct {
+	schema   map[string]entitySchema
+	restored map[string][]map[string]interface{}
+	created  map[string][]map[string]interface{}
+	deleted  map[string][]map[string]interface{}
+	skipped  []string
+}
+
+// snapshotEntities adds the operations restoring the records written or deleted by the sync operations.
+// Records deleted by the push are restored first, then the changed fields are written back and at last the created records are deleted.
+func snapshotEntities(ctx adminSdk.ApiContext, client *adminSdk.Client, operations Operation, snapshot *ConfigSnapshot) error {
+	if len(operations) == 0 {
+		return nil
+	}
+
+	schema, err := fetchEntitySchema(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	s := &entitySnapshot{
+		schema:   schema,
+		restored: map[string][]map[string]interface{}{},
+		created:  map[string][]map[string]interface{}{},
+		deleted:  map[string][]map[string]interface{}{},
+	}
+
+	keys := make([]string, 0, len(operations))
+
+	for key := range operations {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	order := make([]string, 0)
+
+	for _, key := range keys {
+		op := operations[key]
+
+		records, err := syncOperationRecords(op)
+		if err != nil {
+			return err
+		}
+
+		if !containsString(order, op.Entity) {
+			order = append(order, op.Entity)
+		}
+
+		for _, record := range records {
+			if err := s.add(ctx, client, op, record); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, entity := range order {
+		if len(s.deleted[entity]) > 0 {
+			snapshot.Operations.add(syncPhaseRestoreDeleted, adminSdk.SyncOperation{Action: "upsert", Entity: entity, Payload: s.deleted[entity]})
+		}
+	}
+
+	for _, entity := range order {
+		if len(s.restored[entity]) > 0 {
+			snapshot.Operations.add(syncPhaseRestore, adminSdk.SyncOperation{Action: "upsert", Entity: entity, Payload: s.restored[entity]})
+		}
+	}
+
+	createdEntities := make([]string, 0, len(s.created))
+
+	for entity := range s.created {
+		createdEntities = append(createdEntities, entity)
+	}
+
+	sort.Strings(createdEntities)
+
+	for _, entity := range createdEntities {
+		snapshot.Operations.add(syncPhaseDeleteCreated, adminSdk.SyncOperation{Action: "delete", Entity: entity, Payload: s.created[entity]})
+	}
+
+	snapshot.Skipped = append(snapshot.Skipped, s.skipped...)
+
+	return nil
+}
+
+func (s *entitySnapshot) add(ctx adminSdk.ApiContext, client *adminSdk.Client, op adminSdk.SyncOperation, record map[string]interface{}) error {
+	id, ok := record["id"].(string)
+	if !ok || id == "" {
+		s.skipped = append(s.skipped, fmt.Sprintf("%s of a %s record without id", op.Action, op.Entity))
+
+		return nil
+	}
+
+	if op.Action == "delete" {
+		criteria := map[string]interface{}{"ids": []string{id}}
+		associations, complete := s.cascadeAssociations(op.Entity, 0)
+
+		if len(associations) > 0 {
+			criteria["associations"] = associations
+		}
+
+		remote, err := searchRecords(ctx, client, op.Entity, criteria)
+		if err != nil {
+			return err
+		}
+
+		if len(remote) > 0 {
+			s.deleted[op.Entity] = append(s.deleted[op.Entity], s.restoreDeleted(op.Entity, remote[0]))
+
+			if !complete {
+				s.skipped = append(s.skipped, fmt.Sprintf("%s %s is restored without the records deleted with it deeper than %d levels", op.Entity, id, snapshotCascadeDepth))
+			}
+		}
+
+		return nil
+	}
+
+	criteria := map[string]interface{}{"ids": []string{id}}
+
+	if associations := s.associations(op.Entity, record); len(associations) > 0 {
+		criteria["associations"] = associations
+	}
+
+	remote, err := searchRecords(ctx, client, op.Entity, criteria)
+	if err != nil {
+		return err
+	}
+
+	if len(remote) == 0 {
+		s.created[op.Entity] = append(s.created[op.Entity], map[string]interface{}{"id": id})
+
+		return nil
+	}
+
+	s.restored[op.Entity] = append(s.restored[op.Entity], s.restore(op.Entity, record, remote[0]))
+
+	return nil
+}
+
+// associations returns the criteria loading all associations used in the payload.
+func (s *entitySnapshot) associations(entity string, payload map[string]interface{}) map[string]interface{} {
+	associations := make(map[string]interface{})
+
+	for field, value := range payload {
+		definition := s.schema[entity].Properties[field]
+		if definition.Type != "association" {
+			continue
+		}
+
+		nested := make(map[string]interface{})
+
+		for _, child := range snapshotChildren(value) {
+			mergeSnapshotAssociations(nested, s.associations(definition.Entity, child))
+		}
+
+		criteria := map[string]interface{}{}
+
+		if len(nested) > 0 {
+			criteria["associations"] = nested
+		}
+
+		associations[field] = criteria
+	}
+
+	return associations
+}
+
+// cascadeAssociations returns the criteria loading the associations, which are deleted together with a record of the entity.
+// It returns false, when the associations are nested deeper than the snapshot loads.
+func (s *entitySnapshot) cascadeAssociations(entity string, depth int) (map[string]interface{}, bool) {
+	associations := make(map[string]interface{})
+	complete := true
+
+	for field, definition := range s.schema[entity].Properties {
+		if !isCascadeDeleteAssociation(definition) {
+			continue
+		}
+
+		if depth >= snapshotCascadeDepth {
+			return associations, false
+		}
+
+		criteria := map[string]interface{}{}
+
+		// the references of a many to many association are restored, the referenced records are not deleted
+		if definition.Relation != "many_to_many" {
+			nested, nestedComplete := s.cascadeAssociations(definition.Entity, depth+1)
+			complete = complete && nestedComplete
+
+			if len(nested) > 0 {
+				criteria["associations"] = nested
+			}
+		}
+
+		associations[field] = criteria
+	}
+
+	return associations, complete
+}
+
+// restoreDeleted returns the payload recreating the deleted record together with the records deleted with it.
+func (s *entitySnapshot) restoreDeleted(entity string, record map[string]interface{}) map[string]interface{} {
+	restored := cleanPulledEntity(record, s.schema[entity], nil)
+
+	for field, definition := range s.schema[entity].Properties {
+		if !isCascadeDeleteAssociation(definition) {
+			continue
+		}
+
+		if child, ok := record[field].(map[string]interface{}); ok {
+			restored[field] = s.restoreDeleted(definition.Entity, child)
+
+			continue
+		}
+
+		children := make([]interface{}, 0)
+
+		for _, child := range snapshotChildren(record[field]) {
+			if definition.Relation == "many_to_many" {
+				children = append(children, map[string]interface{}{"id": child["id"]})
+			} else {
+				children = append(children, s.restoreDeleted(definition.Entity, child))
+			}
+		}
+
+		if len(children) > 0 {
+			restored[field] = children
+		}
+	}
+
+	return restored
+}
+
+func isCascadeDeleteAssociation(field entitySchemaField) bool {
+	if field.Type != "association" {
+		return false
+	}
+
+	_, ok := field.Flags["cascade_delete"]
+
+	return ok
+}
+
+// restore returns the remote values of the fields in the payload. Nested records created by the push are collected to be deleted.
+func (s *entitySnapshot) restore(entity string, payload, remote map[string]interface{}) map[string]interface{} {
+	restored := make(map[string]interface{}, len(payload))
+
+	for field, value := range payload {
+		definition := s.schema[entity].Properties[field]
+
+		if definition.Type != "association" {
+			restored[field] = remote[field]
+
+			continue
+		}
+
+		remoteChildren := snapshotChildren(remote[field])
+
+		switch definition.Relation {
+		case "one_to_many", "many_to_many":
+			children := make([]interface{}, 0)
+			withoutId := make(map[string]bool)
+
+			for _, child := range snapshotChildren(value) {
+				id, ok := child["id"].(string)
+				if !ok {
+					for key := range child {
+						withoutId[key] = true
+					}
+
+					continue
+				}
+
+				remoteChild := findSnapshotChild(remoteChildren, id)
+
+				if remoteChild != nil {
+					children = append(children, s.restore(definition.Entity, child, remoteChild))
+				} else if definition.Relation == "many_to_many" {
+					s.created[definition.Mapping] = append(s.created[definition.Mapping], map[string]interface{}{definition.Local: payload["id"], definition.Reference: id})
+				} else {
+					s.created[definition.Entity] = append(s.created[definition.Entity], map[string]interface{}{"id": id})
+				}
+			}
+
+			// records without id like translations are restored completely
+			if len(withoutId) > 0 {
+				withoutId["languageId"] = true
+
+				for _, remoteChild := range remoteChildren {
+					child := make(map[string]interface{})
+
+					for key := range withoutId {
+						if v, ok := remoteChild[key]; ok {
+							child[key] = v
+						}
+					}
+
+					children = append(children, child)
+				}
+			}
+
+			restored[field] = children
+		default:
+			child, ok := value.(map[string]interface{})
+			remoteChild, remoteOk := remote[field].(map[string]interface{})
+
+			if ok && remoteOk {
+				restored[field] = s.restore(definition.Entity, child, remoteChild)
+			} else if id, hasId := child["id"].(string); ok && hasId {
+				s.created[definition.Entity] = append(s.created[definition.Entity], map[string]interface{}{"id": id})
+			}
+		}
+	}
+
+	return restored
+}
+
+func snapshotChildren(value interface{}) []map[string]interface{} {
+	children := make([]map[string]interface{}, 0)
+
+	if list, ok := value.([]interface{}); ok {
+		for _, item := range list {
+			if child, ok := item.(map[string]interface{}); ok {
+				children = append(children, child)
+			}
+		}
+	}
+
+	return children
+}
+
+func findSnapshotChild(children []map[string]interface{}, id string) map[string]interface{} {
+	for _, child := range children {
+		if child["id"] == id {
+			return child
+		}
+	}
+
+	return nil
+}
+
+func mergeSnapshotAssociations(target, source map[string]interface{}) {
+	for key, value := range source {
+		existing, ok := target[key].(map[string]interface{})
+		if !ok {
+			target[key] = value
+
+			continue
+		}
+
+		sourceNested, _ := value.(map[string]interface{})["associations"].(map[string]interface{})
+		if len(sourceNested) == 0 {
+			continue
+		}
+
+		targetNested, ok := existing["associations"].(map[string]interface{})
+		if !ok {
+			targetNested = map[string]interface{}{}
+			existing["associations"] = targetNested
+		}
+
+		mergeSnapshotAssociations(targetNested, sourceNested)
+	}
+}
+
+// syncOperationRecords returns the payload of the operation as plain maps.
+func syncOperationRecords(op adminSdk.SyncOperation) ([]map[string]interface{}, error) {
+	content, err := json.Marshal(op.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []map[string]interface{}
+
+	if err := json.Unmarshal(content, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func configSnapshotPath() string {
+	return filepath.Join(filepath.Dir(projectConfigPath), configSnapshotDir)
+}
+
+// writeConfigSnapshot stores the snapshot in a file named after its creation time.
+func writeConfigSnapshot(snapshot *ConfigSnapshot) (string, error) {
+	if err := os.MkdirAll(configSnapshotPath(), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	content, err := json.MarshalIndent(snapshot, "", "    ")
+	if err != nil {
+		return "", err
+	}
+
+	file := filepath.Join(configSnapshotPath(), snapshot.CreatedAt.Format("20060102-150405")+".json")
+
+	// the snapshot can contain secrets of the system config
+	if err := os.WriteFile(file, content, 0o600); err != nil {
+		return "", err
+	}
+
+	return file, nil
+}
+
+func readConfigSnapshot(file string) (*ConfigSnapshot, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read snapshot: %w", err)
+	}
+
+	var snapshot ConfigSnapshot
+
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return nil, fmt.Errorf("cannot read snapshot %s: %w", file, err)
+	}
+
+	return &snapshot, nil
+}
+
+// latestConfigSnapshot returns the newest snapshot file.
+func latestConfigSnapshot() (string, error) {
+	files, err := filepath.Glob(filepath.Join(configSnapshotPath(), "*.json"))
+	if err != nil {
+		return "", err
+	}
+
+	if len(files) == 0 {
+		return "", fmt.Errorf("no snapshot found in %s, snapshots are created by project config push", configSnapshotPath())
+	}
+
+	sort.Strings(files)
+
+	return files[len(files)-1], nil
+}
+
+// applyConfigSnapshot writes the previous values back to the shop.
+func applyConfigSnapshot(ctx adminSdk.ApiContext, client *adminSdk.Client, snapshot *ConfigSnapshot) error {
+	if snapshot.Operations.HasChanges() {
+		if _, err := client.Bulk.Sync(ctx, snapshot.Operations); err != nil {
+			return err
+		}
+	}
+
+	settings := SystemConfig{}
+
+	for _, entry := range snapshot.SystemSettings {
+		settings[entry.SalesChannel] = entry.Settings
+	}
+
+	if settings.HasChanges() {
+		if _, err := client.SystemConfigManager.UpdateConfig(ctx, settings.ToJson()); err != nil {
+			return err
+		}
+	}
+
+	for _, themeOp := range snapshot.ThemeSettings {
+		if len(themeOp.Settings) == 0 {
+			continue
+		}
+
+		if _, err := client.ThemeManager.UpdateConfiguration(ctx, themeOp.Id, adminSdk.ThemeUpdateRequest{Config: themeOp.Settings}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// describeConfigSnapshot lists what a rollback restores.
+func describeConfigSnapshot(snapshot *ConfigSnapshot) []string {
+	lines := make([]string, 0)
+
+	keys := make([]string, 0, len(snapshot.Operations))
+
+	for key := range snapshot.Operations {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		op := snapshot.Operations[key]
+
+		records, _ := syncOperationRecords(op)
+
+		verb := "restore"
+		if op.Action == "delete" {
+			verb = "delete"
+		}
+
+		lines = append(lines, fmt.Sprintf("%s %d %s records", verb, len(records), op.Entity))
+	}
+
+	for _, entry := range snapshot.SystemSettings {
+		salesChannel := "default"
+		if entry.SalesChannel != nil {
+			salesChannel = *entry.SalesChannel
+		}
+
+		keys := make([]string, 0, len(entry.Settings))
+
+		for key := range entry.Settings {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		lines = append(lines, fmt.Sprintf("restore system_config of %s: %s", salesChannel, strings.Join(keys, ", ")))
+	}
+
+	for _, themeOp := range snapshot.ThemeSettings {
+		lines = append(lines, fmt.Sprintf("restore %d settings of theme %s", len(themeOp.Settings), themeOp.Name))
+	}
+
+	return lines
+}
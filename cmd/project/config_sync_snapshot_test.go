@@ -0,0 +1,228 @@
+package project
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func snapshotTestSchema() map[string]entitySchema {
+	return map[string]entitySchema{
+		"cms_page": {Properties: map[string]entitySchemaField{
+			"id":           {Type: "uuid"},
+			"name":         {Type: "string"},
+			"sections":     {Type: "association", Relation: "one_to_many", Entity: "cms_section"},
+			"translations": {Type: "association", Relation: "one_to_many", Entity: "cms_page_translation"},
+		}},
+		"cms_section": {Properties: map[string]entitySchemaField{
+			"id":     {Type: "uuid"},
+			"type":   {Type: "string"},
+			"blocks": {Type: "association", Relation: "one_to_many", Entity: "cms_block"},
+		}},
+		"cms_block": {Properties: map[string]entitySchemaField{
+			"id":   {Type: "uuid"},
+			"type": {Type: "string"},
+		}},
+		"product": {Properties: map[string]entitySchemaField{
+			"id":   {Type: "uuid"},
+			"tags": {Type: "association", Relation: "many_to_many", Entity: "tag", Mapping: "product_tag", Local: "productId", Reference: "tagId"},
+		}},
+	}
+}
+
+func TestEntitySnapshotRestore(t *testing.T) {
+	s := &entitySnapshot{schema: snapshotTestSchema(), created: map[string][]map[string]interface{}{}}
+
+	payload := map[string]interface{}{
+		"id":   "page",
+		"name": "New name",
+		"sections": []interface{}{
+			map[string]interface{}{"id": "section", "type": "sidebar", "blocks": []interface{}{
+				map[string]interface{}{"id": "block", "type": "image"},
+				map[string]interface{}{"id": "new-block", "type": "text"},
+			}},
+		},
+		"translations": []interface{}{
+			map[string]interface{}{"languageId": "de", "name": "Neuer Name"},
+		},
+	}
+
+	remote := map[string]interface{}{
+		"id":        "page",
+		"name":      "Old name",
+		"createdAt": "2024-01-01",
+		"sections": []interface{}{
+			map[string]interface{}{"id": "section", "type": "default", "position": 0.0, "blocks": []interface{}{
+				map[string]interface{}{"id": "block", "type": "text", "position": 0.0},
+			}},
+		},
+		"translations": []interface{}{
+			map[string]interface{}{"languageId": "de", "name": "Alter Name", "cmsPageId": "page"},
+		},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"sections":     map[string]interface{}{"associations": map[string]interface{}{"blocks": map[string]interface{}{}}},
+		"translations": map[string]interface{}{},
+	}, s.associations("cms_page", payload))
+
+	assert.Equal(t, map[string]interface{}{
+		"id":   "page",
+		"name": "Old name",
+		"sections": []interface{}{
+			map[string]interface{}{"id": "section", "type": "default", "blocks": []interface{}{
+				map[string]interface{}{"id": "block", "type": "text"},
+			}},
+		},
+		"translations": []interface{}{
+			map[string]interface{}{"languageId": "de", "name": "Alter Name"},
+		},
+	}, s.restore("cms_page", payload, remote))
+
+	assert.Equal(t, map[string][]map[string]interface{}{"cms_block": {{"id": "new-block"}}}, s.created)
+
+	t.Run("new many to many references are deleted from the mapping", func(t *testing.T) {
+		s := &entitySnapshot{schema: snapshotTestSchema(), created: map[string][]map[string]interface{}{}}
+
+		s.restore("product", map[string]interface{}{
+			"id":   "product",
+			"tags": []interface{}{map[string]interface{}{"id": "old"}, map[string]interface{}{"id": "new"}},
+		}, map[string]interface{}{
+			"id":   "product",
+			"tags": []interface{}{map[string]interface{}{"id": "old", "name": "Old"}},
+		})
+
+		assert.Equal(t, map[string][]map[string]interface{}{"product_tag": {{"productId": "product", "tagId": "new"}}}, s.created)
+	})
+}
+
+func TestEntitySnapshotRestoresCascadedRecords(t *testing.T) {
+	cascade := map[string]interface{}{"cascade_delete": true}
+
+	schema := map[string]entitySchema{
+		"cms_section": {Properties: map[string]entitySchemaField{
+			"id":     {Type: "uuid"},
+			"pageId": {Type: "uuid"},
+			"page":   {Type: "association", Relation: "many_to_one", Entity: "cms_page"},
+			"blocks": {Type: "association", Relation: "one_to_many", Entity: "cms_block", Flags: cascade},
+		}},
+		"cms_block": {Properties: map[string]entitySchemaField{
+			"id":    {Type: "uuid"},
+			"slots": {Type: "association", Relation: "one_to_many", Entity: "cms_slot", Flags: cascade},
+		}},
+		"cms_slot": {Properties: map[string]entitySchemaField{
+			"id":   {Type: "uuid"},
+			"tags": {Type: "association", Relation: "many_to_many", Entity: "tag", Mapping: "cms_slot_tag", Flags: cascade},
+		}},
+	}
+
+	client, requests := newTestAdminClient(t, map[string]interface{}{
+		"/api/search/cms_section": map[string]interface{}{"data": []map[string]interface{}{{
+			"id":        "section",
+			"pageId":    "page",
+			"createdAt": "2024-01-01",
+			"blocks": []interface{}{map[string]interface{}{
+				"id":        "block",
+				"sectionId": "section",
+				"slots": []interface{}{map[string]interface{}{
+					"id":      "slot",
+					"blockId": "block",
+					"tags":    []interface{}{map[string]interface{}{"id": "tag", "name": "Tag"}},
+				}},
+			}},
+		}}},
+	})
+
+	s := &entitySnapshot{schema: schema, deleted: map[string][]map[string]interface{}{}}
+
+	assert.NoError(t, s.add(adminSdk.NewApiContext(context.Background()), client, adminSdk.SyncOperation{Action: "delete", Entity: "cms_section"}, map[string]interface{}{"id": "section"}))
+
+	assert.Equal(t, map[string]interface{}{
+		"blocks": map[string]interface{}{"associations": map[string]interface{}{
+			"slots": map[string]interface{}{"associations": map[string]interface{}{"tags": map[string]interface{}{}}},
+		}},
+	}, requests["/api/search/cms_section"]["associations"])
+
+	assert.Equal(t, []map[string]interface{}{{
+		"id":     "section",
+		"pageId": "page",
+		"blocks": []interface{}{map[string]interface{}{
+			"id":        "block",
+			"sectionId": "section",
+			"slots": []interface{}{map[string]interface{}{
+				"id":      "slot",
+				"blockId": "block",
+				"tags":    []interface{}{map[string]interface{}{"id": "tag"}},
+			}},
+		}},
+	}}, s.deleted["cms_section"])
+	assert.Empty(t, s.skipped)
+
+	t.Run("too deep associations are reported", func(t *testing.T) {
+		s := &entitySnapshot{schema: map[string]entitySchema{
+			"category": {Properties: map[string]entitySchemaField{
+				"children": {Type: "association", Relation: "one_to_many", Entity: "category", Flags: cascade},
+			}},
+		}}
+
+		_, complete := s.cascadeAssociations("category", 0)
+
+		assert.False(t, complete)
+	})
+}
+
+func TestConfigSnapshotFiles(t *testing.T) {
+	previous := projectConfigPath
+	projectConfigPath = filepath.Join(t.TempDir(), ".shopware-project.yml")
+
+	defer func() {
+		projectConfigPath = previous
+	}()
+
+	_, err := latestConfigSnapshot()
+	assert.ErrorContains(t, err, "no snapshot found")
+
+	salesChannel := "storefront"
+
+	for i, createdAt := range []time.Time{time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)} {
+		snapshot := &ConfigSnapshot{
+			CreatedAt: createdAt,
+			URL:       "http://localhost",
+			Operations: Operation{"08-000000-upsert-tax": {
+				Action:  "upsert",
+				Entity:  "tax",
+				Payload: []map[string]interface{}{{"id": "tax", "taxRate": 19.0}},
+			}},
+			SystemSettings: []SystemConfigSnapshot{{SalesChannel: &salesChannel, Settings: map[string]interface{}{"core.basicInformation.email": nil}}},
+			ThemeSettings: ThemeSettings{{Id: "theme", Name: "Storefront", Settings: map[string]adminSdk.ThemeConfigValue{
+				"sw-color-brand-primary": {Value: "#000"},
+			}}},
+		}
+
+		file, err := writeConfigSnapshot(snapshot)
+		assert.NoError(t, err)
+
+		if i == 0 {
+			assert.Equal(t, filepath.Join(configSnapshotPath(), "20240102-100000.json"), file)
+		}
+	}
+
+	latest, err := latestConfigSnapshot()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(configSnapshotPath(), "20240102-100000.json"), latest)
+
+	snapshot, err := readConfigSnapshot(latest)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "storefront", *snapshot.SystemSettings[0].SalesChannel)
+	assert.Equal(t, "#000", snapshot.ThemeSettings[0].Settings["sw-color-brand-primary"].Value)
+	assert.Equal(t, []string{
+		"restore 1 tax records",
+		"restore system_config of storefront: core.basicInformation.email",
+		"restore 1 settings of theme Storefront",
+	}, describeConfigSnapshot(snapshot))
+}
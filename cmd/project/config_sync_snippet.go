@@ -0,0 +1,300 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+	"gopkg.in/yaml.v3"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+const snippetAuthor = "shopware-cli"
+
+type SnippetSync struct{}
+
+func (SnippetSync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, config *shop.Config, operation *ConfigSyncOperation) error {
+	if len(config.Sync.Snippet) == 0 {
+		return nil
+	}
+
+	sets, err := fetchSnippetSets(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	snippetUpdates := make([]map[string]interface{}, 0)
+
+	for _, configEntry := range config.Sync.Snippet {
+		set := findSnippetSet(sets, configEntry.Set)
+		if set == nil {
+			return fmt.Errorf("snippet set %s does not exist", configEntry.Set)
+		}
+
+		local, err := readSnippetFile(configEntry.File)
+		if err != nil {
+			return err
+		}
+
+		remote, err := fetchSnippets(ctx, client, set.Id)
+		if err != nil {
+			return err
+		}
+
+		snippetUpdates = append(snippetUpdates, diffSnippets(*set, local, remote, operation)...)
+	}
+
+	if len(snippetUpdates) > 0 {
+		operation.AddOperation(syncPhaseSnippet, adminSdk.SyncOperation{
+			Action:  "upsert",
+			Entity:  "snippet",
+			Payload: snippetUpdates,
+		})
+	}
+
+	return nil
+}
+
+func (SnippetSync) Pull(ctx adminSdk.ApiContext, client *adminSdk.Client, config *shop.Config) error {
+	sets, err := fetchSnippetSets(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	files := make(map[string]string)
+
+	for _, entry := range config.Sync.Snippet {
+		files[entry.Set] = entry.File
+	}
+
+	duplicateIsos := getDuplicateSnippetSetIsos(sets)
+	pulled := make([]shop.SnippetSync, 0)
+
+	for _, set := range sets {
+		snippets, err := fetchSnippets(ctx, client, set.Id)
+		if err != nil {
+			return err
+		}
+
+		file, ok := files[set.Name]
+
+		if !ok && len(snippets) == 0 {
+			continue
+		}
+
+		if !ok {
+			file = fmt.Sprintf(".shopware-cli/snippet/%s.json", set.Iso)
+
+			if duplicateIsos[set.Iso] {
+				file = fmt.Sprintf(".shopware-cli/snippet/%s-%s.json", set.Iso, snippetFileName(set.Name))
+			}
+		}
+
+		values := make(map[string]string, len(snippets))
+
+		for _, snippet := range snippets {
+			values[snippet.TranslationKey] = snippet.Value
+		}
+
+		if err := writeSnippetFile(file, values); err != nil {
+			return err
+		}
+
+		logging.FromContext(ctx.Context).Infof("Pulled %d snippets of %s into %s", len(values), set.Name, file)
+
+		pulled = append(pulled, shop.SnippetSync{Set: set.Name, File: file})
+	}
+
+	config.Sync.Snippet = pulled
+
+	return nil
+}
+
+// diffSnippets returns the snippet upserts for all local values which are new or differ from the shop. Snippets missing locally are kept.
+func diffSnippets(set adminSdk.SnippetSet, local map[string]string, remote []adminSdk.Snippet, operation *ConfigSyncOperation) []map[string]interface{} {
+	existing := make(map[string]adminSdk.Snippet, len(remote))
+
+	for _, snippet := range remote {
+		existing[snippet.TranslationKey] = snippet
+	}
+
+	keys := make([]string, 0, len(local))
+
+	for key := range local {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	updates := make([]map[string]interface{}, 0)
+
+	for _, key := range keys {
+		value := local[key]
+
+		snippet, ok := existing[key]
+
+		if ok {
+			operation.TrackRemote(shop.SyncOptionSnippet, set.Name, key, snippet.Value)
+		} else {
+			operation.TrackRemote(shop.SyncOptionSnippet, set.Name, key, nil)
+		}
+
+		if operation.KeepsRemote(shop.SyncOptionSnippet, set.Name, key) {
+			continue
+		}
+
+		if !ok {
+			// a fixed id allows project config rollback to delete the snippet again
+			updates = append(updates, map[string]interface{}{
+				"id":             shop.NewUuid(),
+				"setId":          set.Id,
+				"translationKey": key,
+				"value":          value,
+				"author":         snippetAuthor,
+			})
+			operation.AddChange(shop.SyncOptionSnippet, set.Name, key, nil, value)
+
+			continue
+		}
+
+		if snippet.Value != value {
+			updates = append(updates, map[string]interface{}{
+				"id":    snippet.Id,
+				"value": value,
+			})
+			operation.AddChange(shop.SyncOptionSnippet, set.Name, key, snippet.Value, value)
+		}
+	}
+
+	return updates
+}
+
+func findSnippetSet(sets []adminSdk.SnippetSet, name string) *adminSdk.SnippetSet {
+	for i := range sets {
+		if sets[i].Name == name {
+			return &sets[i]
+		}
+	}
+
+	return nil
+}
+
+func getDuplicateSnippetSetIsos(sets []adminSdk.SnippetSet) map[string]bool {
+	check := make(map[string]bool)
+	duplicates := make(map[string]bool)
+
+	for _, set := range sets {
+		if check[set.Iso] {
+			duplicates[set.Iso] = true
+		}
+
+		check[set.Iso] = true
+	}
+
+	return duplicates
+}
+
+func snippetFileName(name string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+
+		return '-'
+	}, name), "-")
+}
+
+func isYamlFile(file string) bool {
+	ext := filepath.Ext(file)
+
+	return ext == ".yml" || ext == ".yaml"
+}
+
+// readSnippetFile reads a flat map of translation keys to values from a JSON or YAML file.
+func readSnippetFile(file string) (map[string]string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read snippet file %s: %w", file, err)
+	}
+
+	values := make(map[string]string)
+
+	if isYamlFile(file) {
+		err = yaml.Unmarshal(content, &values)
+	} else {
+		err = json.Unmarshal(content, &values)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read snippet file %s: %w", file, err)
+	}
+
+	return values, nil
+}
+
+func writeSnippetFile(file string, values map[string]string) error {
+	var content []byte
+	var err error
+
+	if isYamlFile(file) {
+		content, err = yaml.Marshal(values)
+	} else {
+		content, err = json.MarshalIndent(values, "", "    ")
+		content = append(content, '\n')
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, content, os.ModePerm)
+}
+
+func fetchSnippetSets(ctx adminSdk.ApiContext, client *adminSdk.Client) ([]adminSdk.SnippetSet, error) {
+	criteria := adminSdk.Criteria{}
+	criteria.Includes = map[string][]string{"snippet_set": {"id", "name", "iso"}}
+
+	collection, resp, err := client.Repository.SnippetSet.SearchAll(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(collection.Data, func(i, j int) bool {
+		return collection.Data[i].Name < collection.Data[j].Name
+	})
+
+	return collection.Data, nil
+}
+
+func fetchSnippets(ctx adminSdk.ApiContext, client *adminSdk.Client, setId string) ([]adminSdk.Snippet, error) {
+	criteria := adminSdk.Criteria{}
+	criteria.Includes = map[string][]string{"snippet": {"id", "translationKey", "value"}}
+	criteria.Filter = []adminSdk.CriteriaFilter{
+		{Type: adminSdk.SearchFilterTypeEquals, Field: "setId", Value: setId},
+	}
+
+	collection, resp, err := client.Repository.Snippet.SearchAll(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		return nil, err
+	}
+
+	return collection.Data, nil
+}
@@ -0,0 +1,58 @@
+package project
+
+import (
+	"path/filepath"
+	"testing"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSnippets(t *testing.T) {
+	set := adminSdk.SnippetSet{Id: "set", Name: "BASE de-DE", Iso: "de-DE"}
+	remote := []adminSdk.Snippet{
+		{Id: "a", TranslationKey: "header.title", Value: "Alt"},
+		{Id: "b", TranslationKey: "footer.title", Value: "Fuß"},
+		{Id: "c", TranslationKey: "only.remote", Value: "Bleibt"},
+	}
+	local := map[string]string{
+		"header.title": "Neu",
+		"footer.title": "Fuß",
+		"new.key":      "Neuer Text",
+	}
+
+	operation := NewConfigSyncOperation()
+	updates := diffSnippets(set, local, remote, operation)
+
+	assert.Len(t, updates[1]["id"], 32)
+	delete(updates[1], "id")
+
+	assert.Equal(t, []map[string]interface{}{
+		{"id": "a", "value": "Neu"},
+		{"setId": "set", "translationKey": "new.key", "value": "Neuer Text", "author": snippetAuthor},
+	}, updates)
+	assert.Len(t, operation.Changes, 2)
+	assert.Equal(t, ConfigChange{Applier: "snippet", Target: "BASE de-DE", Field: "header.title", Before: "Alt", After: "Neu"}, operation.Changes[0])
+}
+
+func TestSnippetFileRoundTrip(t *testing.T) {
+	values := map[string]string{"header.title": "Titel", "footer.title": "Fuß"}
+
+	for _, name := range []string{"de-DE.json", "de-DE.yml"} {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "snippet", name)
+
+			assert.NoError(t, writeSnippetFile(file, values))
+
+			read, err := readSnippetFile(file)
+
+			assert.NoError(t, err)
+			assert.Equal(t, values, read)
+		})
+	}
+}
+
+func TestSnippetFileName(t *testing.T) {
+	assert.Equal(t, "BASE-de-DE", snippetFileName("BASE de-DE"))
+	assert.Equal(t, "My-Shop", snippetFileName("My/Shop!"))
+}
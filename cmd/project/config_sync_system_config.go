@@ -31,6 +31,12 @@ func (SystemConfigSync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, c
 	}()
 
 	for _, config := range config.Sync.Config {
+		target := "default"
+
+		if config.SalesChannel != nil {
+			target = *config.SalesChannel
+		}
+
 		if config.SalesChannel != nil && len(*config.SalesChannel) != 32 {
 			foundId := false
 
@@ -67,11 +73,14 @@ func (SystemConfigSync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, c
 				if existingConfig.ConfigurationKey == newK {
 					foundKey = true
 
+					operation.TrackRemote(shop.SyncOptionSystemConfig, target, newK, existingConfig.ConfigurationValue)
+
 					encodedSource, _ := json.Marshal(existingConfig.ConfigurationValue)
 					encodedTarget, _ := json.Marshal(newV)
 
-					if !bytes.Equal(encodedSource, encodedTarget) {
+					if !bytes.Equal(encodedSource, encodedTarget) && !operation.KeepsRemote(shop.SyncOptionSystemConfig, target, newK) {
 						operation.SystemSettings[config.SalesChannel][newK] = newV
+						operation.AddChange(shop.SyncOptionSystemConfig, target, newK, existingConfig.ConfigurationValue, newV)
 					}
 
 					break
@@ -79,7 +88,12 @@ func (SystemConfigSync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, c
 			}
 
 			if !foundKey {
+				operation.TrackRemote(shop.SyncOptionSystemConfig, target, newK, nil)
+			}
+
+			if !foundKey && !operation.KeepsRemote(shop.SyncOptionSystemConfig, target, newK) {
 				operation.SystemSettings[config.SalesChannel][newK] = newV
+				operation.AddChange(shop.SyncOptionSystemConfig, target, newK, nil, newV)
 			}
 		}
 	}
@@ -0,0 +1,39 @@
+package project
+
+import (
+	"sort"
+	"testing"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigSyncOperationOrder(t *testing.T) {
+	operation := NewConfigSyncOperation()
+
+	for i := 0; i < 1000; i++ {
+		operation.AddOperation(syncPhaseEntity, adminSdk.SyncOperation{Action: "upsert", Entity: "tax"})
+	}
+
+	operation.AddOperation(syncPhaseCms, adminSdk.SyncOperation{Action: "upsert", Entity: "cms_page"})
+	operation.AddOperation(syncPhaseCms, adminSdk.SyncOperation{Action: "delete", Entity: "cms_section"})
+	operation.AddOperation(syncPhaseSnippet, adminSdk.SyncOperation{Action: "upsert", Entity: "snippet"})
+
+	keys := make([]string, 0, len(operation.Operations))
+
+	for key := range operation.Operations {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	entities := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		if entity := operation.Operations[key].Entity; len(entities) == 0 || entities[len(entities)-1] != entity {
+			entities = append(entities, entity)
+		}
+	}
+
+	assert.Equal(t, []string{"cms_page", "cms_section", "tax", "snippet"}, entities)
+}
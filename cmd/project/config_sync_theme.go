@@ -50,14 +50,22 @@ func (ThemeSync) Push(ctx adminSdk.ApiContext, client *adminSdk.Client, config *
 					Settings: map[string]adminSdk.ThemeConfigValue{},
 				}
 
+				settings, err := resolveThemeMedia(ctx, client, localThemeConfig.Settings, operation)
+				if err != nil {
+					return err
+				}
+
 				for remoteFieldName, remoteFieldValue := range *remoteConfigs.CurrentFields {
-					for localFieldName, localFieldValue := range localThemeConfig.Settings {
+					for localFieldName, localFieldValue := range settings {
 						if remoteFieldName == localFieldName {
+							operation.TrackRemote(shop.SyncOptionTheme, t.Name, remoteFieldName, remoteFieldValue.Value)
+
 							localJson, _ := json.Marshal(localFieldValue)
 							remoteJson, _ := json.Marshal(remoteFieldValue)
 
-							if !bytes.Equal(localJson, remoteJson) {
+							if !bytes.Equal(localJson, remoteJson) && !operation.KeepsRemote(shop.SyncOptionTheme, t.Name, remoteFieldName) {
 								op.Settings[remoteFieldName] = localFieldValue
+								operation.AddChange(shop.SyncOptionTheme, t.Name, remoteFieldName, remoteFieldValue.Value, localFieldValue.Value)
 							}
 						}
 					}
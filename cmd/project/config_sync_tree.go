@@ -0,0 +1,631 @@
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+	"gopkg.in/yaml.v3"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+const treePageSize = 25
+
+// treeDefinition describes an entity whose children reference each other with a parentId, like the flow sequences or rule conditions.
+// The children are stored as flat list in the shop and as nested tree in the files.
+type treeDefinition struct {
+	Entity string
+	Fields []string
+	// Identifier are the fields to find the record in another shop, when its id does not exist there
+	Identifier  []string
+	ChildField  string
+	ChildEntity string
+	ChildFields []string
+	// NestedField holds the children of a child in the files
+	NestedField string
+	// Phase orders the sync operations of the records
+	Phase     syncPhase
+	Directory string
+}
+
+type entityReference struct {
+	Entity string
+	Field  string
+}
+
+// treeReferences are fields holding ids of other records, which are written as lookup on a portable field.
+var treeReferences = map[string]entityReference{
+	"ruleId":            {Entity: "rule", Field: "name"},
+	"mailTemplateId":    {Entity: "mail_template", Field: "mailTemplateType.technicalName"},
+	"salesChannelIds":   {Entity: "sales_channel", Field: "name"},
+	"customerGroupId":   {Entity: "customer_group", Field: "name"},
+	"customerGroupIds":  {Entity: "customer_group", Field: "name"},
+	"paymentMethodIds":  {Entity: "payment_method", Field: "name"},
+	"shippingMethodIds": {Entity: "shipping_method", Field: "name"},
+	"currencyIds":       {Entity: "currency", Field: "isoCode"},
+	"countryIds":        {Entity: "country", Field: "iso"},
+	"languageIds":       {Entity: "language", Field: "name"},
+	"identifiers":       {Entity: "product", Field: "productNumber"},
+}
+
+func pushTrees(ctx adminSdk.ApiContext, client *adminSdk.Client, definition treeDefinition, files []string, operation *ConfigSyncOperation) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	search := newEntityLookupSearch(ctx, client, operation)
+	upserts := make([]map[string]interface{}, 0)
+	deletes := make([]map[string]interface{}, 0)
+
+	for _, file := range files {
+		local, err := readTreeFile(file)
+		if err != nil {
+			return err
+		}
+
+		resolved, err := resolveEntityLookups(local, operation.lookups, search)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", definition.Entity, file, err)
+		}
+
+		normalized, err := normalizeRecord(resolved)
+		if err != nil {
+			return err
+		}
+
+		payload, err := flattenTreeRecord(definition, normalized)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", definition.Entity, file, err)
+		}
+
+		remote, err := findTreeRecord(ctx, client, definition, payload)
+		if err != nil {
+			return err
+		}
+
+		var current map[string]interface{}
+
+		if remote != nil {
+			payload["id"] = remote["id"]
+			current = cleanTreeRecord(definition, remote)
+		}
+
+		desired := cleanTreeRecord(definition, payload)
+
+		name := fmt.Sprintf("%v", payload["name"])
+
+		operation.TrackRemote(definition.Entity, name, definition.Entity, current)
+
+		if reflect.DeepEqual(current, desired) || operation.KeepsRemote(definition.Entity, name, definition.Entity) {
+			continue
+		}
+
+		if current == nil {
+			operation.AddChange(definition.Entity, name, definition.Entity, nil, desired)
+		} else {
+			operation.AddChange(definition.Entity, name, definition.Entity, current, desired)
+
+			for _, id := range removedTreeChildIds(definition, remote, payload) {
+				deletes = append(deletes, map[string]interface{}{"id": id})
+			}
+		}
+
+		upserts = append(upserts, payload)
+	}
+
+	if len(upserts) > 0 {
+		operation.AddOperation(definition.Phase, adminSdk.SyncOperation{
+			Action:  "upsert",
+			Entity:  definition.Entity,
+			Payload: upserts,
+		})
+	}
+
+	// the removed children are deleted after the upsert, so moved children get their new parent first
+	if len(deletes) > 0 {
+		operation.AddOperation(definition.Phase, adminSdk.SyncOperation{
+			Action:  "delete",
+			Entity:  definition.ChildEntity,
+			Payload: deletes,
+		})
+	}
+
+	return nil
+}
+
+// pullTrees writes all records into files and returns the file names. Records keep the file they have been written to before.
+func pullTrees(ctx adminSdk.ApiContext, client *adminSdk.Client, definition treeDefinition, existing []string) ([]string, error) {
+	records := make([]map[string]interface{}, 0)
+
+	for page := 1; ; page++ {
+		data, err := fetchTreeRecords(ctx, client, definition, map[string]interface{}{
+			"page":  page,
+			"limit": treePageSize,
+			"sort":  []map[string]string{{"field": "name"}, {"field": "id"}},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, data...)
+
+		if len(data) < treePageSize {
+			break
+		}
+	}
+
+	files := make(map[string]string)
+
+	for _, file := range existing {
+		if record, err := readTreeFile(file); err == nil {
+			if id, ok := record["id"].(string); ok {
+				files[id] = file
+			}
+		}
+	}
+
+	trees := make([]map[string]interface{}, 0, len(records))
+	references := make(map[entityReference]map[string]bool)
+
+	for _, record := range records {
+		tree := cleanTreeRecord(definition, record)
+
+		mapTreeReferences(tree, func(ref entityReference, id string) interface{} {
+			if references[ref] == nil {
+				references[ref] = make(map[string]bool)
+			}
+
+			references[ref][id] = true
+
+			return id
+		})
+
+		trees = append(trees, tree)
+	}
+
+	names, err := fetchReferenceNames(ctx, client, references)
+	if err != nil {
+		return nil, err
+	}
+
+	usedFiles := make(map[string]bool)
+	result := make([]string, 0, len(trees))
+
+	for _, tree := range trees {
+		id, _ := tree["id"].(string)
+		name, _ := tree["name"].(string)
+
+		mapTreeReferences(tree, func(ref entityReference, refId string) interface{} {
+			value, ok := names[ref][refId]
+			if !ok {
+				return refId
+			}
+
+			return map[string]interface{}{entityLookupKey: map[string]interface{}{"entity": ref.Entity, "field": ref.Field, "value": value}}
+		})
+
+		file, ok := files[id]
+
+		if !ok {
+			file = fmt.Sprintf("%s/%s.yml", definition.Directory, snippetFileName(name))
+
+			if snippetFileName(name) == "" || usedFiles[file] {
+				file = fmt.Sprintf("%s/%s-%s.yml", definition.Directory, snippetFileName(name), id)
+			}
+		}
+
+		usedFiles[file] = true
+
+		if err := writeTreeFile(definition, file, tree); err != nil {
+			return nil, err
+		}
+
+		result = append(result, file)
+	}
+
+	logging.FromContext(ctx.Context).Infof("Pulled %d %s records", len(trees), definition.Entity)
+
+	return result, nil
+}
+
+// cleanTreeRecord returns the writable fields of the record with the children nested by their parentId.
+func cleanTreeRecord(definition treeDefinition, record map[string]interface{}) map[string]interface{} {
+	cleaned := make(map[string]interface{})
+
+	for _, field := range definition.Fields {
+		if value, ok := record[field]; ok && value != nil {
+			cleaned[field] = value
+		}
+	}
+
+	items, _ := record[definition.ChildField].([]interface{})
+	children := make([]map[string]interface{}, 0, len(items))
+
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			children = append(children, m)
+		}
+	}
+
+	cleaned[definition.ChildField] = nestTreeChildren(definition, children, nil)
+
+	return cleaned
+}
+
+func nestTreeChildren(definition treeDefinition, children []map[string]interface{}, parentId interface{}) []interface{} {
+	nested := make([]map[string]interface{}, 0)
+
+	for _, child := range children {
+		if child["parentId"] != parentId {
+			continue
+		}
+
+		node := make(map[string]interface{})
+
+		for _, field := range definition.ChildFields {
+			if value, ok := child[field]; ok && value != nil {
+				node[field] = value
+			}
+		}
+
+		if grandChildren := nestTreeChildren(definition, children, child["id"]); len(grandChildren) > 0 {
+			node[definition.NestedField] = grandChildren
+		}
+
+		nested = append(nested, node)
+	}
+
+	sort.SliceStable(nested, func(i, j int) bool {
+		return treeSortKey(nested[i]) < treeSortKey(nested[j])
+	})
+
+	result := make([]interface{}, len(nested))
+
+	for i, node := range nested {
+		result[i] = node
+	}
+
+	return result
+}
+
+// treeSortKey orders the true case of a flow before the false case, then by position.
+func treeSortKey(node map[string]interface{}) string {
+	trueCase := "1"
+
+	if node["trueCase"] == true {
+		trueCase = "0"
+	}
+
+	return fmt.Sprintf("%s-%012.3f-%v", trueCase, cmsPosition(node), node["id"])
+}
+
+// flattenTreeRecord returns the api payload of a record read from a file, with the nested children as flat list.
+func flattenTreeRecord(definition treeDefinition, record map[string]interface{}) (map[string]interface{}, error) {
+	payload := make(map[string]interface{})
+
+	for _, field := range definition.Fields {
+		if value, ok := record[field]; ok && value != nil {
+			payload[field] = value
+		}
+	}
+
+	if _, ok := payload["id"].(string); !ok {
+		return nil, fmt.Errorf("the %s has no id", definition.Entity)
+	}
+
+	nested, _ := record[definition.ChildField].([]interface{})
+	flat := make([]interface{}, 0)
+
+	if err := flattenTreeChildren(definition, nested, nil, &flat); err != nil {
+		return nil, err
+	}
+
+	payload[definition.ChildField] = flat
+
+	return payload, nil
+}
+
+func flattenTreeChildren(definition treeDefinition, nested []interface{}, parentId interface{}, flat *[]interface{}) error {
+	for _, item := range nested {
+		node, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		child := make(map[string]interface{})
+
+		for _, field := range definition.ChildFields {
+			if value, ok := node[field]; ok && value != nil {
+				child[field] = value
+			}
+		}
+
+		if _, ok := child["id"].(string); !ok {
+			return fmt.Errorf("every %s needs an id", definition.ChildEntity)
+		}
+
+		// the root children are written with an empty parentId too, so a moved child is detached from its old parent
+		child["parentId"] = parentId
+
+		*flat = append(*flat, child)
+
+		grandChildren, _ := node[definition.NestedField].([]interface{})
+
+		if err := flattenTreeChildren(definition, grandChildren, child["id"], flat); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removedTreeChildIds returns the ids of the children in the shop which are not in the payload. Children of removed children are deleted with them.
+func removedTreeChildIds(definition treeDefinition, remote, payload map[string]interface{}) []string {
+	keep := make(map[interface{}]bool)
+
+	items, _ := payload[definition.ChildField].([]interface{})
+
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			keep[m["id"]] = true
+		}
+	}
+
+	removed := make(map[interface{}]bool)
+	remoteItems, _ := remote[definition.ChildField].([]interface{})
+
+	for _, item := range remoteItems {
+		if m, ok := item.(map[string]interface{}); ok && !keep[m["id"]] {
+			removed[m["id"]] = true
+		}
+	}
+
+	ids := make([]string, 0)
+
+	for _, item := range remoteItems {
+		m, ok := item.(map[string]interface{})
+		if !ok || !removed[m["id"]] || removed[m["parentId"]] {
+			continue
+		}
+
+		if id, ok := m["id"].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// mapTreeReferences replaces the ids in all fields of treeReferences with the result of replace.
+func mapTreeReferences(value interface{}, replace func(ref entityReference, id string) interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			ref, ok := treeReferences[key]
+			if !ok {
+				v[key] = mapTreeReferences(item, replace)
+
+				continue
+			}
+
+			switch ids := item.(type) {
+			case string:
+				if ids != "" {
+					v[key] = replace(ref, ids)
+				}
+			case []interface{}:
+				for i, id := range ids {
+					if s, ok := id.(string); ok && s != "" {
+						ids[i] = replace(ref, s)
+					}
+				}
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = mapTreeReferences(item, replace)
+		}
+	}
+
+	return value
+}
+
+// fetchReferenceNames returns the value of the reference field for each referenced id.
+func fetchReferenceNames(ctx adminSdk.ApiContext, client *adminSdk.Client, references map[entityReference]map[string]bool) (map[entityReference]map[string]interface{}, error) {
+	names := make(map[entityReference]map[string]interface{})
+
+	for ref, idSet := range references {
+		ids := make([]string, 0, len(idSet))
+
+		for id := range idSet {
+			ids = append(ids, id)
+		}
+
+		sort.Strings(ids)
+
+		criteria := map[string]interface{}{"ids": ids}
+		path := strings.Split(ref.Field, ".")
+
+		if len(path) > 1 {
+			criteria["associations"] = map[string]interface{}{path[0]: map[string]interface{}{}}
+		}
+
+		records, err := searchRecords(ctx, client, ref.Entity, criteria)
+		if err != nil {
+			return nil, err
+		}
+
+		names[ref] = make(map[string]interface{})
+
+		for _, record := range records {
+			id, _ := record["id"].(string)
+
+			if value := recordValue(record, path); value != nil && value != "" {
+				names[ref][id] = value
+			}
+		}
+	}
+
+	return names, nil
+}
+
+func recordValue(record map[string]interface{}, path []string) interface{} {
+	var value interface{} = record
+
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		value = m[key]
+	}
+
+	return value
+}
+
+// findTreeRecord returns the record with the id of the payload or, when it does not exist, the record with the same identifier fields.
+func findTreeRecord(ctx adminSdk.ApiContext, client *adminSdk.Client, definition treeDefinition, payload map[string]interface{}) (map[string]interface{}, error) {
+	records, err := fetchTreeRecords(ctx, client, definition, map[string]interface{}{"ids": []interface{}{payload["id"]}})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) > 0 {
+		return records[0], nil
+	}
+
+	filter := make([]shop.EntitySyncFilter, 0, len(definition.Identifier))
+
+	for _, field := range definition.Identifier {
+		if payload[field] == nil {
+			return nil, nil
+		}
+
+		filter = append(filter, shop.EntitySyncFilter{Type: "equals", Field: field, Value: payload[field]})
+	}
+
+	records, err = fetchTreeRecords(ctx, client, definition, map[string]interface{}{"filter": filter})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) > 1 {
+		return nil, fmt.Errorf("found %d %s records with the same %s, use the id of the record", len(records), definition.Entity, strings.Join(definition.Identifier, " and "))
+	}
+
+	if len(records) == 1 {
+		return records[0], nil
+	}
+
+	return nil, nil
+}
+
+func fetchTreeRecords(ctx adminSdk.ApiContext, client *adminSdk.Client, definition treeDefinition, criteria map[string]interface{}) ([]map[string]interface{}, error) {
+	criteria["associations"] = map[string]interface{}{definition.ChildField: map[string]interface{}{}}
+
+	return searchRecords(ctx, client, definition.Entity, criteria)
+}
+
+func readTreeFile(file string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", file, err)
+	}
+
+	var record map[string]interface{}
+
+	if err := yaml.Unmarshal(content, &record); err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", file, err)
+	}
+
+	return record, nil
+}
+
+func writeTreeFile(definition treeDefinition, file string, tree map[string]interface{}) error {
+	node, err := treeNode(definition.Fields, tree, definition.ChildField, func(child map[string]interface{}) (*yaml.Node, error) {
+		return treeChildNode(definition, child)
+	})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(node); err != nil {
+		return err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, buf.Bytes(), os.ModePerm)
+}
+
+func treeChildNode(definition treeDefinition, child map[string]interface{}) (*yaml.Node, error) {
+	return treeNode(definition.ChildFields, child, definition.NestedField, func(grandChild map[string]interface{}) (*yaml.Node, error) {
+		return treeChildNode(definition, grandChild)
+	})
+}
+
+// treeNode builds the YAML node of the record with the fields in the given order and the children last.
+func treeNode(fields []string, record map[string]interface{}, childField string, childNode func(map[string]interface{}) (*yaml.Node, error)) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+
+	for _, field := range fields {
+		value, ok := record[field]
+		if !ok {
+			continue
+		}
+
+		var valueNode yaml.Node
+
+		if err := valueNode.Encode(value); err != nil {
+			return nil, err
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field}, &valueNode)
+	}
+
+	items, ok := record[childField].([]interface{})
+	if !ok {
+		return node, nil
+	}
+
+	children := &yaml.Node{Kind: yaml.SequenceNode}
+
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		n, err := childNode(m)
+		if err != nil {
+			return nil, err
+		}
+
+		children.Content = append(children.Content, n)
+	}
+
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: childField}, children)
+
+	return node, nil
+}
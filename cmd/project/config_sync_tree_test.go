@@ -0,0 +1,171 @@
+package project
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+func flowTestRecord() map[string]interface{} {
+	return map[string]interface{}{
+		"id":        "flow",
+		"name":      "Order placed",
+		"eventName": "checkout.order.placed",
+		"active":    true,
+		"invalid":   false,
+		"createdAt": "2024-01-01",
+		"sequences": []interface{}{
+			map[string]interface{}{"id": "mail", "parentId": "if", "trueCase": true, "position": 1.0, "actionName": "action.mail.send", "config": map[string]interface{}{"mailTemplateId": "template"}, "flowId": "flow"},
+			map[string]interface{}{"id": "tag", "parentId": "if", "trueCase": false, "position": 1.0, "actionName": "action.add.order.tag", "config": map[string]interface{}{"tagIds": map[string]interface{}{"t": "VIP"}}},
+			map[string]interface{}{"id": "if", "parentId": nil, "trueCase": false, "position": 1.0, "ruleId": "rule", "actionName": nil},
+		},
+	}
+}
+
+func TestCleanTreeRecord(t *testing.T) {
+	cleaned := cleanTreeRecord(flowDefinition, flowTestRecord())
+
+	assert.NotContains(t, cleaned, "invalid")
+	assert.NotContains(t, cleaned, "createdAt")
+
+	sequences := cleaned["sequences"].([]interface{})
+	assert.Len(t, sequences, 1)
+
+	root := sequences[0].(map[string]interface{})
+	assert.Equal(t, "if", root["id"])
+	assert.NotContains(t, root, "actionName")
+
+	children := root["sequences"].([]interface{})
+	assert.Len(t, children, 2)
+	assert.Equal(t, "mail", children[0].(map[string]interface{})["id"])
+	assert.Equal(t, "tag", children[1].(map[string]interface{})["id"])
+	assert.NotContains(t, children[0], "parentId")
+	assert.NotContains(t, children[0], "flowId")
+}
+
+func TestFlattenTreeRecord(t *testing.T) {
+	cleaned := cleanTreeRecord(flowDefinition, flowTestRecord())
+
+	payload, err := flattenTreeRecord(flowDefinition, cleaned)
+	assert.NoError(t, err)
+
+	sequences := payload["sequences"].([]interface{})
+	assert.Len(t, sequences, 3)
+	assert.Contains(t, sequences[0], "parentId")
+	assert.Nil(t, sequences[0].(map[string]interface{})["parentId"])
+	assert.Equal(t, "if", sequences[1].(map[string]interface{})["parentId"])
+
+	assert.Equal(t, cleaned, cleanTreeRecord(flowDefinition, payload))
+
+	t.Run("nested child moved to the root", func(t *testing.T) {
+		payload, err := flattenTreeRecord(ruleDefinition, map[string]interface{}{"id": "rule", "conditions": []interface{}{
+			map[string]interface{}{"id": "and", "type": "andContainer"},
+			map[string]interface{}{"id": "moved", "type": "alwaysValid"},
+		}})
+		assert.NoError(t, err)
+
+		conditions := payload["conditions"].([]interface{})
+		assert.Len(t, conditions, 2)
+		assert.Equal(t, "moved", conditions[1].(map[string]interface{})["id"])
+		assert.Contains(t, conditions[1], "parentId")
+		assert.Nil(t, conditions[1].(map[string]interface{})["parentId"])
+	})
+
+	t.Run("children need an id", func(t *testing.T) {
+		_, err := flattenTreeRecord(flowDefinition, map[string]interface{}{"id": "flow", "sequences": []interface{}{map[string]interface{}{"actionName": "x"}}})
+
+		assert.ErrorContains(t, err, "needs an id")
+	})
+}
+
+func TestRemovedTreeChildIds(t *testing.T) {
+	remote := flowTestRecord()
+	payload := map[string]interface{}{"sequences": []interface{}{map[string]interface{}{"id": "other"}}}
+
+	assert.Equal(t, []string{"if"}, removedTreeChildIds(flowDefinition, remote, payload))
+}
+
+func TestMapTreeReferences(t *testing.T) {
+	record := cleanTreeRecord(ruleDefinition, map[string]interface{}{
+		"id":   "rule",
+		"name": "Rule",
+		"conditions": []interface{}{
+			map[string]interface{}{"id": "a", "type": "salesChannel", "value": map[string]interface{}{"operator": "=", "salesChannelIds": []interface{}{"sc1", "sc2"}}},
+		},
+	})
+
+	mapTreeReferences(record, func(ref entityReference, id string) interface{} {
+		return ref.Entity + ":" + id
+	})
+
+	condition := record["conditions"].([]interface{})[0].(map[string]interface{})
+
+	assert.Equal(t, []interface{}{"sales_channel:sc1", "sales_channel:sc2"}, condition["value"].(map[string]interface{})["salesChannelIds"])
+	assert.Equal(t, "=", condition["value"].(map[string]interface{})["operator"])
+}
+
+func TestTreeFileRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "flow", "order.yml")
+	tree := cleanTreeRecord(flowDefinition, flowTestRecord())
+
+	assert.NoError(t, writeTreeFile(flowDefinition, file, tree))
+
+	read, err := readTreeFile(file)
+	assert.NoError(t, err)
+
+	normalized, err := normalizeRecord(read)
+	assert.NoError(t, err)
+
+	payload, err := flattenTreeRecord(flowDefinition, normalized)
+	assert.NoError(t, err)
+
+	assert.Equal(t, tree, cleanTreeRecord(flowDefinition, payload))
+}
+
+func TestRulePushWithoutChanges(t *testing.T) {
+	remote := map[string]interface{}{
+		"id":        "rule",
+		"name":      "Rule",
+		"priority":  1.0,
+		"createdAt": "2024-01-01",
+		"conditions": []interface{}{
+			map[string]interface{}{"id": "or", "parentId": nil, "type": "orContainer", "position": 0.0, "ruleId": "rule"},
+			map[string]interface{}{"id": "channel", "parentId": "or", "type": "salesChannel", "position": 0.0, "value": map[string]interface{}{"operator": "=", "salesChannelIds": []interface{}{"sc1"}}},
+		},
+	}
+
+	file := filepath.Join(t.TempDir(), "rule.yml")
+	assert.NoError(t, writeTreeFile(ruleDefinition, file, cleanTreeRecord(ruleDefinition, remote)))
+
+	client, _ := newTestAdminClient(t, map[string]interface{}{
+		"/api/search/rule": map[string]interface{}{"data": []interface{}{remote}},
+	})
+
+	operation := NewConfigSyncOperation()
+	cfg := &shop.Config{Sync: &shop.ConfigSync{Rule: []shop.RuleDefinition{{File: file}}}}
+
+	assert.NoError(t, RuleSync{}.Push(adminSdk.NewApiContext(context.Background()), client, cfg, operation))
+	assert.Empty(t, operation.Operations)
+	assert.Empty(t, operation.Changes)
+}
+
+func TestFlowPushWithoutChanges(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "flow.yml")
+	assert.NoError(t, writeTreeFile(flowDefinition, file, cleanTreeRecord(flowDefinition, flowTestRecord())))
+
+	client, _ := newTestAdminClient(t, map[string]interface{}{
+		"/api/search/flow": map[string]interface{}{"data": []interface{}{flowTestRecord()}},
+	})
+
+	operation := NewConfigSyncOperation()
+	cfg := &shop.Config{Sync: &shop.ConfigSync{Flow: []shop.FlowDefinition{{File: file}}}}
+
+	assert.NoError(t, FlowSync{}.Push(adminSdk.NewApiContext(context.Background()), client, cfg, operation))
+	assert.Empty(t, operation.Operations)
+	assert.Empty(t, operation.Changes)
+}
@@ -0,0 +1,146 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/spf13/cobra"
+
+	"github.com/FriendsOfShopware/shopware-cli/internal/phpexec"
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+const deploymentVersionKey = "deployment.version"
+
+// mysqlErrUnknownDatabase is returned by MySQL when the configured database does not exist.
+const mysqlErrUnknownDatabase = 1049
+
+type deployment struct {
+	projectRoot string
+	config      *shop.ConfigDeployment
+	db          *sql.DB
+}
+
+func newDeployment(cmd *cobra.Command) (*deployment, error) {
+	projectRoot, err := findClosestShopwareProject()
+	if err != nil {
+		return nil, err
+	}
+
+	projectCfg, err := readProjectConfig(true)
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentCfg := projectCfg.ConfigDeployment
+	if deploymentCfg == nil {
+		deploymentCfg = &shop.ConfigDeployment{}
+	}
+
+	mysqlConfig, err := assembleConnectionURI(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("mysql", mysqlConfig.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+
+	return &deployment{projectRoot: projectRoot, config: deploymentCfg, db: db}, nil
+}
+
+func (d deployment) Close(ctx context.Context) {
+	if err := d.db.Close(); err != nil {
+		logging.FromContext(ctx).Errorf("Deployment: %v", err)
+	}
+}
+
+func (d deployment) console(ctx context.Context, args ...string) error {
+	logging.FromContext(ctx).Infof("Running bin/console %v", args)
+
+	consoleCmd := phpexec.ConsoleCommand(ctx, args...)
+	consoleCmd.Dir = d.projectRoot
+	consoleCmd.Stdout = os.Stdout
+	consoleCmd.Stderr = os.Stderr
+
+	if err := consoleCmd.Run(); err != nil {
+		return fmt.Errorf("bin/console %s failed: %w", args[0], err)
+	}
+
+	return nil
+}
+
+func (d deployment) hook(ctx context.Context, name, script string) error {
+	if script == "" {
+		return nil
+	}
+
+	logging.FromContext(ctx).Infof("Running %s hook", name)
+
+	hookCmd := d.shellCommand(ctx, script)
+	hookCmd.Stdout = os.Stdout
+	hookCmd.Stderr = os.Stderr
+
+	if err := hookCmd.Run(); err != nil {
+		return fmt.Errorf("%s hook failed: %w", name, err)
+	}
+
+	return nil
+}
+
+func (d deployment) shellCommand(ctx context.Context, script string) *exec.Cmd {
+	shellCmd := exec.CommandContext(ctx, "sh", "-c", script)
+	shellCmd.Dir = d.projectRoot
+	shellCmd.Env = append(os.Environ(), fmt.Sprintf("PROJECT_ROOT=%s", d.projectRoot))
+
+	return shellCmd
+}
+
+func (d deployment) isInstalled(ctx context.Context) (bool, error) {
+	var count int
+
+	err := d.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'system_config'").Scan(&count)
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrUnknownDatabase {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (d deployment) deployedVersion(ctx context.Context) (string, error) {
+	var value string
+
+	err := d.db.QueryRowContext(ctx, "SELECT configuration_value FROM system_config WHERE configuration_key = ? AND sales_channel_id IS NULL", deploymentVersionKey).Scan(&value)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	var decoded struct {
+		Value string `json:"_value"`
+	}
+
+	if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+		return "", err
+	}
+
+	return decoded.Value, nil
+}
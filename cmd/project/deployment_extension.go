@@ -0,0 +1,247 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/FriendsOfShopware/shopware-cli/extension"
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+	"github.com/FriendsOfShopware/shopware-cli/version"
+)
+
+const (
+	extensionActionInstall    = "install"
+	extensionActionUpdate     = "update"
+	extensionActionActivate   = "activate"
+	extensionActionDeactivate = "deactivate"
+	extensionActionUninstall  = "uninstall"
+
+	extensionOverrideInactive = "inactive"
+	extensionOverrideRemove   = "remove"
+	extensionOverrideIgnore   = "ignore"
+)
+
+type localExtension struct {
+	Name    string
+	Type    string
+	Version string
+}
+
+type installedExtension struct {
+	Name    string
+	Type    string
+	Version string
+	Active  bool
+}
+
+type extensionPlanStep struct {
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Action       string `json:"action"`
+	FromVersion  string `json:"fromVersion,omitempty"`
+	ToVersion    string `json:"toVersion,omitempty"`
+	KeepUserData bool   `json:"keepUserData,omitempty"`
+}
+
+type extensionPlan []extensionPlanStep
+
+func computeExtensionPlan(local []localExtension, installed map[string]installedExtension, overrides shop.ConfigDeploymentOverrides, exclude []string) extensionPlan {
+	plan := make(extensionPlan, 0)
+
+	local = slices.Clone(local)
+
+	sort.Slice(local, func(i, j int) bool {
+		return local[i].Name < local[j].Name
+	})
+
+	for _, ext := range local {
+		if slices.Contains(exclude, ext.Name) {
+			continue
+		}
+
+		override := overrides[ext.Name]
+
+		if override.State == extensionOverrideIgnore {
+			continue
+		}
+
+		current, isInstalled := installed[ext.Name]
+
+		if override.State == extensionOverrideRemove {
+			if isInstalled {
+				plan = append(plan, extensionPlanStep{Name: ext.Name, Type: ext.Type, Action: extensionActionUninstall, FromVersion: current.Version, KeepUserData: override.KeepUserData})
+			}
+
+			continue
+		}
+
+		shouldBeActive := override.State != extensionOverrideInactive
+
+		if !isInstalled {
+			plan = append(plan, extensionPlanStep{Name: ext.Name, Type: ext.Type, Action: extensionActionInstall, ToVersion: ext.Version})
+
+			if shouldBeActive {
+				plan = append(plan, extensionPlanStep{Name: ext.Name, Type: ext.Type, Action: extensionActionActivate})
+			}
+
+			continue
+		}
+
+		if isNewerExtensionVersion(current.Version, ext.Version) {
+			plan = append(plan, extensionPlanStep{Name: ext.Name, Type: ext.Type, Action: extensionActionUpdate, FromVersion: current.Version, ToVersion: ext.Version})
+		}
+
+		if shouldBeActive && !current.Active {
+			plan = append(plan, extensionPlanStep{Name: ext.Name, Type: ext.Type, Action: extensionActionActivate})
+		}
+
+		if !shouldBeActive && current.Active {
+			plan = append(plan, extensionPlanStep{Name: ext.Name, Type: ext.Type, Action: extensionActionDeactivate})
+		}
+	}
+
+	return plan
+}
+
+func isNewerExtensionVersion(installed, local string) bool {
+	installedVersion, installedErr := version.NewVersion(installed)
+	localVersion, localErr := version.NewVersion(local)
+
+	if installedErr != nil || localErr != nil {
+		return installed != local
+	}
+
+	return localVersion.GreaterThan(installedVersion)
+}
+
+func (step extensionPlanStep) consoleArgs() []string {
+	prefix := "plugin"
+
+	if step.Type == extension.TypePlatformApp {
+		prefix = "app"
+	}
+
+	args := []string{fmt.Sprintf("%s:%s", prefix, step.Action)}
+
+	if step.Action == extensionActionUninstall && step.KeepUserData {
+		args = append(args, "--keep-user-data")
+	}
+
+	return append(args, step.Name)
+}
+
+func (p extensionPlan) Render(w io.Writer, asJson bool) error {
+	if asJson {
+		content, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(w, string(content))
+
+		return err
+	}
+
+	if len(p) == 0 {
+		_, err := fmt.Fprintln(w, "All extensions are up to date")
+
+		return err
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetColWidth(100)
+	table.SetHeader([]string{"Name", "Type", "Action", "From", "To"})
+
+	for _, step := range p {
+		table.Append([]string{step.Name, step.Type, step.Action, step.FromVersion, step.ToVersion})
+	}
+
+	table.Render()
+
+	return nil
+}
+
+func (d deployment) extensionPlan(ctx context.Context) (extensionPlan, error) {
+	local := make([]localExtension, 0)
+
+	for _, ext := range extension.FindExtensionsFromProject(ctx, d.projectRoot) {
+		if ext.GetType() != extension.TypePlatformPlugin && ext.GetType() != extension.TypePlatformApp {
+			continue
+		}
+
+		name, err := ext.GetName()
+		if err != nil {
+			return nil, err
+		}
+
+		extVersion, err := ext.GetVersion()
+		if err != nil {
+			return nil, err
+		}
+
+		local = append(local, localExtension{Name: name, Type: ext.GetType(), Version: extVersion.String()})
+	}
+
+	installed, err := d.installedExtensions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return computeExtensionPlan(local, installed, d.config.ExtensionManagement.Overrides, d.config.ExtensionManagement.Exclude), nil
+}
+
+func (d deployment) installedExtensions(ctx context.Context) (map[string]installedExtension, error) {
+	installed := make(map[string]installedExtension)
+
+	queries := map[string]string{
+		extension.TypePlatformPlugin: "SELECT name, version, active FROM plugin WHERE installed_at IS NOT NULL",
+		extension.TypePlatformApp:    "SELECT name, version, active FROM app",
+	}
+
+	for extType, query := range queries {
+		rows, err := d.db.QueryContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			ext := installedExtension{Type: extType}
+
+			if err := rows.Scan(&ext.Name, &ext.Version, &ext.Active); err != nil {
+				_ = rows.Close()
+				return nil, err
+			}
+
+			installed[ext.Name] = ext
+		}
+
+		if err := rows.Close(); err != nil {
+			return nil, err
+		}
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return installed, nil
+}
+
+func (d deployment) applyExtensionPlan(ctx context.Context, plan extensionPlan) error {
+	for _, step := range plan {
+		logging.FromContext(ctx).Infof("%s %s %s", step.Action, step.Type, step.Name)
+
+		if err := d.console(ctx, step.consoleArgs()...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+func TestComputeExtensionPlan(t *testing.T) {
+	t.Run("install and activate new extensions", func(t *testing.T) {
+		plan := computeExtensionPlan([]localExtension{{Name: "FroshTools", Type: "plugin", Version: "1.0.0"}}, map[string]installedExtension{}, nil, nil)
+
+		assert.Equal(t, extensionPlan{
+			{Name: "FroshTools", Type: "plugin", Action: extensionActionInstall, ToVersion: "1.0.0"},
+			{Name: "FroshTools", Type: "plugin", Action: extensionActionActivate},
+		}, plan)
+	})
+
+	t.Run("update and activate installed extensions", func(t *testing.T) {
+		installed := map[string]installedExtension{
+			"FroshTools": {Name: "FroshTools", Type: "plugin", Version: "1.0.0", Active: false},
+			"MyApp":      {Name: "MyApp", Type: "app", Version: "2.0.0", Active: true},
+		}
+
+		plan := computeExtensionPlan([]localExtension{
+			{Name: "MyApp", Type: "app", Version: "2.0.0"},
+			{Name: "FroshTools", Type: "plugin", Version: "1.1.0"},
+		}, installed, nil, nil)
+
+		assert.Equal(t, extensionPlan{
+			{Name: "FroshTools", Type: "plugin", Action: extensionActionUpdate, FromVersion: "1.0.0", ToVersion: "1.1.0"},
+			{Name: "FroshTools", Type: "plugin", Action: extensionActionActivate},
+		}, plan)
+	})
+
+	t.Run("overrides and excludes", func(t *testing.T) {
+		installed := map[string]installedExtension{
+			"Inactive": {Name: "Inactive", Type: "plugin", Version: "1.0.0", Active: true},
+			"Removed":  {Name: "Removed", Type: "plugin", Version: "1.0.0", Active: true},
+			"Ignored":  {Name: "Ignored", Type: "plugin", Version: "1.0.0", Active: false},
+		}
+
+		overrides := shop.ConfigDeploymentOverrides{
+			"Inactive": {State: extensionOverrideInactive},
+			"Removed":  {State: extensionOverrideRemove, KeepUserData: true},
+			"Ignored":  {State: extensionOverrideIgnore},
+		}
+
+		plan := computeExtensionPlan([]localExtension{
+			{Name: "Inactive", Type: "plugin", Version: "1.0.0"},
+			{Name: "Removed", Type: "plugin", Version: "1.0.0"},
+			{Name: "Ignored", Type: "plugin", Version: "2.0.0"},
+			{Name: "Excluded", Type: "plugin", Version: "1.0.0"},
+		}, installed, overrides, []string{"Excluded"})
+
+		assert.Equal(t, extensionPlan{
+			{Name: "Inactive", Type: "plugin", Action: extensionActionDeactivate},
+			{Name: "Removed", Type: "plugin", Action: extensionActionUninstall, FromVersion: "1.0.0", KeepUserData: true},
+		}, plan)
+
+		assert.Equal(t, []string{"plugin:uninstall", "--keep-user-data", "Removed"}, plan[1].consoleArgs())
+	})
+
+	t.Run("installed extensions outside of the project are kept", func(t *testing.T) {
+		installed := map[string]installedExtension{
+			"Kept":     {Name: "Kept", Type: "plugin", Version: "1.0.0", Active: true},
+			"StoreApp": {Name: "StoreApp", Type: "app", Version: "2.0.0", Active: true},
+		}
+
+		local := []localExtension{{Name: "Kept", Type: "plugin", Version: "1.0.0"}, {Name: "A", Type: "plugin", Version: "1.0.0"}}
+
+		plan := computeExtensionPlan(local, installed, shop.ConfigDeploymentOverrides{}, nil)
+
+		assert.Equal(t, extensionPlan{
+			{Name: "A", Type: "plugin", Action: extensionActionInstall, ToVersion: "1.0.0"},
+			{Name: "A", Type: "plugin", Action: extensionActionActivate},
+		}, plan)
+
+		// the extensions of the caller are not reordered
+		assert.Equal(t, "Kept", local[0].Name)
+	})
+}
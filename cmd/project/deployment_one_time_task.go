@@ -0,0 +1,235 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+)
+
+const (
+	oneTimeTaskTable      = "shopware_cli_one_time_task"
+	oneTimeTaskTimeFormat = "2006-01-02 15:04:05.000"
+)
+
+type oneTimeTaskExecution struct {
+	Id        string        `json:"id"`
+	StartedAt time.Time     `json:"startedAt"`
+	Duration  time.Duration `json:"duration"`
+	ExitCode  int           `json:"exitCode"`
+	Output    string        `json:"output"`
+}
+
+func (e oneTimeTaskExecution) Succeeded() bool {
+	return e.ExitCode == 0
+}
+
+func (d deployment) ensureOneTimeTaskTable(ctx context.Context) error {
+	_, err := d.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+oneTimeTaskTable+` (
+		id VARCHAR(255) NOT NULL,
+		started_at DATETIME(3) NOT NULL,
+		duration_ms BIGINT NOT NULL,
+		exit_code INT NOT NULL,
+		output LONGTEXT NULL,
+		PRIMARY KEY (id)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+
+	return err
+}
+
+func (d deployment) oneTimeTaskExecutions(ctx context.Context) (map[string]oneTimeTaskExecution, error) {
+	if err := d.ensureOneTimeTaskTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.QueryContext(ctx, "SELECT id, started_at, duration_ms, exit_code, COALESCE(output, '') FROM "+oneTimeTaskTable)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Errorf("oneTimeTaskExecutions: %v", err)
+		}
+	}()
+
+	executions := make(map[string]oneTimeTaskExecution)
+
+	for rows.Next() {
+		var execution oneTimeTaskExecution
+		var startedAt string
+		var durationMs int64
+
+		if err := rows.Scan(&execution.Id, &startedAt, &durationMs, &execution.ExitCode, &execution.Output); err != nil {
+			return nil, err
+		}
+
+		if execution.StartedAt, err = time.Parse(oneTimeTaskTimeFormat, startedAt); err != nil {
+			return nil, err
+		}
+
+		execution.Duration = time.Duration(durationMs) * time.Millisecond
+		executions[execution.Id] = execution
+	}
+
+	return executions, rows.Err()
+}
+
+func (d deployment) saveOneTimeTaskExecution(ctx context.Context, execution oneTimeTaskExecution) error {
+	if err := d.ensureOneTimeTaskTable(ctx); err != nil {
+		return err
+	}
+
+	_, err := d.db.ExecContext(
+		ctx,
+		"REPLACE INTO "+oneTimeTaskTable+" (id, started_at, duration_ms, exit_code, output) VALUES (?, ?, ?, ?, ?)",
+		execution.Id,
+		execution.StartedAt.UTC().Format(oneTimeTaskTimeFormat),
+		execution.Duration.Milliseconds(),
+		execution.ExitCode,
+		execution.Output,
+	)
+
+	return err
+}
+
+func (d deployment) removeOneTimeTaskExecution(ctx context.Context, id string) (bool, error) {
+	if err := d.ensureOneTimeTaskTable(ctx); err != nil {
+		return false, err
+	}
+
+	result, err := d.db.ExecContext(ctx, "DELETE FROM "+oneTimeTaskTable+" WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
+}
+
+func (d deployment) runOneTimeTasks(ctx context.Context) error {
+	if len(d.config.OneTimeTasks) == 0 {
+		return nil
+	}
+
+	executions, err := d.oneTimeTaskExecutions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range d.config.OneTimeTasks {
+		if execution, ok := executions[task.Id]; ok && execution.Succeeded() {
+			logging.FromContext(ctx).Debugf("One-time task %s already executed at %s", task.Id, execution.StartedAt)
+			continue
+		}
+
+		logging.FromContext(ctx).Infof("Running one-time task %s", task.Id)
+
+		execution := d.executeScript(ctx, task.Script)
+		execution.Id = task.Id
+
+		if err := d.saveOneTimeTaskExecution(ctx, execution); err != nil {
+			return err
+		}
+
+		if !execution.Succeeded() {
+			return fmt.Errorf("one-time task %s failed with exit code %d", task.Id, execution.ExitCode)
+		}
+	}
+
+	return nil
+}
+
+func (d deployment) executeScript(ctx context.Context, script string) oneTimeTaskExecution {
+	var output bytes.Buffer
+
+	scriptCmd := d.shellCommand(ctx, script)
+	scriptCmd.Stdout = io.MultiWriter(os.Stdout, &output)
+	scriptCmd.Stderr = io.MultiWriter(os.Stderr, &output)
+
+	execution := oneTimeTaskExecution{StartedAt: time.Now()}
+
+	err := scriptCmd.Run()
+
+	execution.Duration = time.Since(execution.StartedAt)
+
+	var exitErr *exec.ExitError
+
+	if errors.As(err, &exitErr) {
+		execution.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		execution.ExitCode = -1
+		output.WriteString(err.Error())
+	}
+
+	execution.Output = output.String()
+
+	return execution
+}
+
+var errOneTimeTaskNotFound = errors.New("one-time task is not configured")
+
+// oneTimeTaskState is the execution state of a one-time task, as listed by project deploy one-time-task list.
+type oneTimeTaskState struct {
+	Id     string `json:"id"`
+	Status string `json:"status"`
+	// Configured is false for executed tasks, which have been removed from the config
+	Configured bool                  `json:"configured"`
+	Execution  *oneTimeTaskExecution `json:"execution"`
+}
+
+// oneTimeTaskStates returns the configured tasks in the order of the config, followed by the executed tasks which are not configured anymore.
+func (d deployment) oneTimeTaskStates(executions map[string]oneTimeTaskExecution) []oneTimeTaskState {
+	ids := make([]string, 0, len(d.config.OneTimeTasks))
+
+	for _, task := range d.config.OneTimeTasks {
+		ids = append(ids, task.Id)
+	}
+
+	removed := make([]string, 0)
+
+	for id := range executions {
+		if !d.hasOneTimeTask(id) {
+			removed = append(removed, id)
+		}
+	}
+
+	sort.Strings(removed)
+
+	states := make([]oneTimeTaskState, 0, len(ids)+len(removed))
+
+	for _, id := range append(ids, removed...) {
+		state := oneTimeTaskState{Id: id, Status: "pending", Configured: d.hasOneTimeTask(id)}
+
+		if execution, ok := executions[id]; ok {
+			state.Execution = &execution
+			state.Status = "done"
+
+			if !execution.Succeeded() {
+				state.Status = "failed"
+			}
+		}
+
+		states = append(states, state)
+	}
+
+	return states
+}
+
+func (d deployment) hasOneTimeTask(id string) bool {
+	for _, task := range d.config.OneTimeTasks {
+		if task.Id == id {
+			return true
+		}
+	}
+
+	return false
+}
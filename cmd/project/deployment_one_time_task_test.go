@@ -0,0 +1,55 @@
+package project
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+func TestOneTimeTaskExecuteScript(t *testing.T) {
+	d := deployment{projectRoot: t.TempDir()}
+
+	t.Run("successful script", func(t *testing.T) {
+		execution := d.executeScript(context.Background(), "echo hello")
+
+		assert.True(t, execution.Succeeded())
+		assert.Equal(t, "hello\n", execution.Output)
+	})
+
+	t.Run("failing script", func(t *testing.T) {
+		execution := d.executeScript(context.Background(), "echo failed >&2; exit 3")
+
+		assert.False(t, execution.Succeeded())
+		assert.Equal(t, 3, execution.ExitCode)
+		assert.Equal(t, "failed\n", execution.Output)
+	})
+}
+
+func TestOneTimeTaskStates(t *testing.T) {
+	cfg := &shop.ConfigDeployment{}
+	cfg.OneTimeTasks = make([]struct {
+		Id     string `yaml:"id" jsonschema:"required"`
+		Script string `yaml:"script" jsonschema:"required"`
+	}, 3)
+	cfg.OneTimeTasks[0].Id = "migrate"
+	cfg.OneTimeTasks[1].Id = "broken"
+	cfg.OneTimeTasks[2].Id = "pending"
+
+	d := deployment{config: cfg}
+
+	states := d.oneTimeTaskStates(map[string]oneTimeTaskExecution{
+		"migrate": {Id: "migrate"},
+		"broken":  {Id: "broken", ExitCode: 1},
+		"removed": {Id: "removed"},
+	})
+
+	assert.Len(t, states, 4)
+	assert.Equal(t, []string{"migrate", "broken", "pending", "removed"}, []string{states[0].Id, states[1].Id, states[2].Id, states[3].Id})
+	assert.Equal(t, []string{"done", "failed", "pending", "done"}, []string{states[0].Status, states[1].Status, states[2].Status, states[3].Status})
+	assert.Nil(t, states[2].Execution)
+	assert.True(t, states[2].Configured)
+	assert.False(t, states[3].Configured)
+}
@@ -1,11 +1,16 @@
 package project
 
 import (
+	"os"
+
 	"github.com/FriendsOfShopware/shopware-cli/shop"
 	"github.com/spf13/cobra"
 )
 
-var projectConfigPath string
+var (
+	projectConfigPath  string
+	projectEnvironment string
+)
 
 var projectRootCmd = &cobra.Command{
 	Use:   "project",
@@ -15,4 +20,23 @@ var projectRootCmd = &cobra.Command{
 func Register(rootCmd *cobra.Command) {
 	rootCmd.AddCommand(projectRootCmd)
 	projectRootCmd.PersistentFlags().StringVar(&projectConfigPath, "project-config", shop.DefaultConfigFileName(), "Path to config")
+	projectRootCmd.PersistentFlags().StringVar(&projectEnvironment, "env", os.Getenv("SHOPWARE_CLI_ENV"), "Environment of the config to use")
+}
+
+// readProjectConfig reads the project config and applies the environment selected with --env.
+func readProjectConfig(allowFallback bool) (*shop.Config, error) {
+	cfg, err := shop.ReadConfig(projectConfigPath, allowFallback)
+	if err != nil {
+		return nil, err
+	}
+
+	if projectEnvironment == "" {
+		return cfg, nil
+	}
+
+	if err := cfg.UseEnvironment(projectEnvironment); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
 }
@@ -7,7 +7,6 @@ import (
 
 	"github.com/FriendsOfShopware/shopware-cli/extension"
 	"github.com/FriendsOfShopware/shopware-cli/internal/phpexec"
-	"github.com/FriendsOfShopware/shopware-cli/shop"
 	"github.com/spf13/cobra"
 )
 
@@ -28,7 +27,7 @@ var projectAdminWatchCmd = &cobra.Command{
 			return err
 		}
 
-		shopCfg, err := shop.ReadConfig(projectConfigPath, true)
+		shopCfg, err := readProjectConfig(true)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,68 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+	"github.com/spf13/cobra"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+var errConfigDrift = errors.New("the shop configuration differs from the local config")
+
+var projectConfigDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Shows the differences between your local config and the external shop",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		format, _ := cmd.Flags().GetString("format")
+
+		if format != configDiffFormatText && format != configDiffFormatJson && format != configDiffFormatMarkdown {
+			return fmt.Errorf("unsupported format %q, supported are %s, %s and %s", format, configDiffFormatText, configDiffFormatJson, configDiffFormatMarkdown)
+		}
+
+		var cfg *shop.Config
+		var err error
+
+		if cfg, err = readProjectConfig(false); err != nil {
+			return err
+		}
+
+		client, err := shop.NewShopClient(cmd.Context(), cfg)
+		if err != nil {
+			return err
+		}
+
+		operation, err := buildConfigSyncOperation(adminSdk.NewApiContext(cmd.Context()), client, cfg)
+		if err != nil {
+			return err
+		}
+
+		lock, err := readConfigLock()
+		if err != nil {
+			return err
+		}
+
+		markConfigConflicts(lock, cfg.URL, operation)
+
+		if err := configDiff(operation.Changes).Render(os.Stdout, format); err != nil {
+			return err
+		}
+
+		if len(operation.Changes) > 0 {
+			// the drift is no usage error, the diff has been printed already
+			cmd.SilenceUsage = true
+
+			return errConfigDrift
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	projectConfigCmd.AddCommand(projectConfigDiffCmd)
+	projectConfigDiffCmd.Flags().String("format", configDiffFormatText, "Output format (text, json, markdown)")
+}
@@ -1,11 +1,8 @@
 package project
 
 import (
-	"os"
-
 	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 
 	"github.com/FriendsOfShopware/shopware-cli/logging"
 	"github.com/FriendsOfShopware/shopware-cli/shop"
@@ -22,7 +19,18 @@ var projectConfigPullCmd = &cobra.Command{
 			return err
 		}
 
-		client, err := shop.NewShopClient(cmd.Context(), cfg)
+		// the pulled values are written into the base config, the environment only selects the shop
+		shopCfg, err := readProjectConfig(false)
+		if err != nil {
+			return err
+		}
+
+		client, err := shop.NewShopClient(cmd.Context(), shopCfg)
+		if err != nil {
+			return err
+		}
+
+		original, err := cfg.Sync.Clone()
 		if err != nil {
 			return err
 		}
@@ -33,16 +41,19 @@ var projectConfigPullCmd = &cobra.Command{
 			}
 		}
 
-		content, err := yaml.Marshal(cfg)
+		// only the changed sync sections are written, so placeholders, includes and comments stay untouched
+		written, err := shop.WriteSyncConfig(projectConfigPath, original, cfg.Sync)
 		if err != nil {
 			return err
 		}
 
-		if err := os.WriteFile(projectConfigPath, content, os.ModePerm); err != nil {
-			return err
+		if len(written) == 0 {
+			logging.FromContext(cmd.Context()).Infof("%s is already up to date", projectConfigPath)
 		}
 
-		logging.FromContext(cmd.Context()).Infof("%s has been updated", projectConfigPath)
+		for _, file := range written {
+			logging.FromContext(cmd.Context()).Infof("%s has been updated", file)
+		}
 
 		return nil
 	},
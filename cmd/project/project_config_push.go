@@ -2,6 +2,7 @@ package project
 
 import (
 	"encoding/json"
+	"fmt"
 
 	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
 	"github.com/manifoldco/promptui"
@@ -23,8 +24,15 @@ var projectConfigPushCmd = &cobra.Command{
 		apiCtx := adminSdk.NewApiContext(cmd.Context())
 
 		autoApprove, _ := cmd.PersistentFlags().GetBool("auto-approve")
+		conflictResolution, _ := cmd.Flags().GetString("conflict")
 
-		if cfg, err = shop.ReadConfig(projectConfigPath, false); err != nil {
+		if conflictResolution != "" {
+			if err := validateConfigConflictResolution(conflictResolution); err != nil {
+				return err
+			}
+		}
+
+		if cfg, err = readProjectConfig(false); err != nil {
 			return err
 		}
 
@@ -33,15 +41,33 @@ var projectConfigPushCmd = &cobra.Command{
 			return err
 		}
 
-		operation := &ConfigSyncOperation{
-			Operations:     map[string]adminSdk.SyncOperation{},
-			SystemSettings: map[*string]map[string]interface{}{},
-			ThemeSettings:  []ThemeSyncOperation{},
+		operation, err := buildConfigSyncOperation(apiCtx, client, cfg)
+		if err != nil {
+			return err
 		}
 
-		if cfg.Sync != nil {
-			for _, applyer := range NewSyncApplyers(cfg) {
-				if err := applyer.Push(apiCtx, client, cfg, operation); err != nil {
+		lock, err := readConfigLock()
+		if err != nil {
+			return err
+		}
+
+		keep := map[configStateKey]bool{}
+
+		if conflicts := markConfigConflicts(lock, cfg.URL, operation); len(conflicts) > 0 {
+			resolution, err := resolveConfigConflicts(cmd.Context(), conflicts, conflictResolution, autoApprove)
+			if err != nil {
+				return err
+			}
+
+			switch resolution {
+			case configConflictPull:
+				return fmt.Errorf("%d fields have been changed in the shop since the last push, update the local config with project config pull", len(conflicts))
+			case configConflictKeepRemote:
+				for _, conflict := range conflicts {
+					keep[configStateKey{Applier: conflict.Applier, Target: conflict.Target, Field: conflict.Field}] = true
+				}
+
+				if operation, err = buildConfigSyncOperationKeepingRemote(apiCtx, client, cfg, keep); err != nil {
 					return err
 				}
 			}
@@ -49,13 +75,28 @@ var projectConfigPushCmd = &cobra.Command{
 
 		if !operation.HasChanges() {
 			logging.FromContext(cmd.Context()).Infof("Configuration is up to date")
-			return nil
+
+			lock.update(cfg.URL, operation, keep)
+
+			return writeConfigLock(lock)
+		}
+
+		if len(operation.MediaUploads) > 0 {
+			logging.FromContext(cmd.Context()).Infof("Following files will be uploaded as media")
+
+			for _, upload := range operation.MediaUploads {
+				logging.FromContext(cmd.Context()).Infof("%s as %s.%s", upload.Path, upload.FileName, upload.Extension)
+			}
 		}
 
 		if operation.Operations.HasChanges() {
 			logging.FromContext(cmd.Context()).Infof("Following entities will be written")
 
 			for _, values := range operation.Operations {
+				if deletes, ok := values.Payload.([]map[string]interface{}); ok && values.Action == "delete" {
+					logging.FromContext(cmd.Context()).Warnf("%d %s records will be deleted", len(deletes), values.Entity)
+				}
+
 				logging.FromContext(cmd.Context()).Infof("Action: %s, Entity: %s", values.Action, values.Entity)
 
 				content, _ := json.Marshal(values.Payload)
@@ -109,31 +150,98 @@ var projectConfigPushCmd = &cobra.Command{
 			}
 		}
 
-		if _, err := client.Bulk.Sync(apiCtx, operation.Operations); err != nil {
+		snapshot, err := createConfigSnapshot(apiCtx, client, cfg, operation)
+		if err != nil {
 			return err
 		}
 
-		if operation.SystemSettings.HasChanges() {
-			if _, err := client.SystemConfigManager.UpdateConfig(apiCtx, operation.SystemSettings.ToJson()); err != nil {
-				return err
-			}
+		snapshotFile, err := writeConfigSnapshot(snapshot)
+		if err != nil {
+			return err
 		}
 
-		if operation.ThemeSettings.HasChanges() {
-			for _, themeOp := range operation.ThemeSettings {
-				if _, err := client.ThemeManager.UpdateConfiguration(apiCtx, themeOp.Id, adminSdk.ThemeUpdateRequest{Config: themeOp.Settings}); err != nil {
-					return err
-				}
-			}
+		logging.FromContext(cmd.Context()).Infof("Saved the current remote values to %s, restore them with project config rollback", snapshotFile)
+
+		if err := applyConfigSyncOperation(apiCtx, client, operation); err != nil {
+			return offerConfigRollback(apiCtx, client, snapshot, err, autoApprove)
 		}
 
 		logging.FromContext(cmd.Context()).Infof("Configuration has been applied to remote")
 
+		// the lock holds the remote values after the push, so the next push detects changes made in the shop
+		applied, err := buildConfigSyncOperation(apiCtx, client, cfg)
+		if err != nil {
+			return err
+		}
+
+		lock.update(cfg.URL, applied, keep)
+
+		if err := writeConfigLock(lock); err != nil {
+			return err
+		}
+
+		logging.FromContext(cmd.Context()).Infof("Updated %s", configLockPath())
+
 		return nil
 	},
 }
 
+func applyConfigSyncOperation(ctx adminSdk.ApiContext, client *adminSdk.Client, operation *ConfigSyncOperation) error {
+	if err := uploadMedia(ctx, client, operation.MediaUploads); err != nil {
+		return err
+	}
+
+	if operation.Operations.HasChanges() {
+		if _, err := client.Bulk.Sync(ctx, operation.Operations); err != nil {
+			return err
+		}
+	}
+
+	if operation.SystemSettings.HasChanges() {
+		if _, err := client.SystemConfigManager.UpdateConfig(ctx, operation.SystemSettings.ToJson()); err != nil {
+			return err
+		}
+	}
+
+	for _, themeOp := range operation.ThemeSettings {
+		if len(themeOp.Settings) == 0 {
+			continue
+		}
+
+		if _, err := client.ThemeManager.UpdateConfiguration(ctx, themeOp.Id, adminSdk.ThemeUpdateRequest{Config: themeOp.Settings}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// offerConfigRollback restores the snapshot after a failed push. With auto approve the rollback runs without asking.
+func offerConfigRollback(ctx adminSdk.ApiContext, client *adminSdk.Client, snapshot *ConfigSnapshot, pushErr error, autoApprove bool) error {
+	logging.FromContext(ctx.Context).Errorf("Push failed: %v", pushErr)
+
+	if !autoApprove {
+		p := promptui.Prompt{
+			Label:     "Roll back the changes, which have been applied so far?",
+			IsConfirm: true,
+		}
+
+		if _, err := p.Run(); err != nil {
+			return pushErr
+		}
+	}
+
+	if err := applyConfigSnapshot(ctx, client, snapshot); err != nil {
+		return fmt.Errorf("%w, the rollback failed too: %v", pushErr, err)
+	}
+
+	logging.FromContext(ctx.Context).Infof("The previous remote values have been restored")
+
+	return pushErr
+}
+
 func init() {
 	projectConfigCmd.AddCommand(projectConfigPushCmd)
 	projectConfigPushCmd.PersistentFlags().Bool("auto-approve", false, "Skips the confirmation")
+	projectConfigPushCmd.Flags().String("conflict", "", "Resolves fields changed in the shop since the last push without asking (local, remote, pull)")
 }
@@ -0,0 +1,106 @@
+package project
+
+import (
+	"fmt"
+
+	adminSdk "github.com/friendsofshopware/go-shopware-admin-api-sdk"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+var projectConfigRollbackCmd = &cobra.Command{
+	Use:   "rollback [snapshot]",
+	Short: "Restores the remote values saved by a previous push, the latest snapshot by default",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var cfg *shop.Config
+		var err error
+
+		autoApprove, _ := cmd.PersistentFlags().GetBool("auto-approve")
+
+		file := ""
+		if len(args) > 0 {
+			file = args[0]
+		} else if file, err = latestConfigSnapshot(); err != nil {
+			return err
+		}
+
+		snapshot, err := readConfigSnapshot(file)
+		if err != nil {
+			return err
+		}
+
+		if cfg, err = readProjectConfig(false); err != nil {
+			return err
+		}
+
+		if snapshot.URL != cfg.URL {
+			return fmt.Errorf("the snapshot %s was taken from %s, but the config points to %s", file, snapshot.URL, cfg.URL)
+		}
+
+		if !snapshot.HasChanges() {
+			logging.FromContext(cmd.Context()).Infof("The snapshot %s has nothing to restore", file)
+
+			return nil
+		}
+
+		logging.FromContext(cmd.Context()).Infof("Rolling back to the remote values of %s", snapshot.CreatedAt.Format("2006-01-02 15:04:05"))
+
+		for _, line := range describeConfigSnapshot(snapshot) {
+			logging.FromContext(cmd.Context()).Infof("%s", line)
+		}
+
+		for _, skipped := range snapshot.Skipped {
+			logging.FromContext(cmd.Context()).Warnf("Cannot be restored: %s", skipped)
+		}
+
+		if !autoApprove {
+			p := promptui.Prompt{
+				Label:     "You want to restore these values in your Shop?",
+				IsConfirm: true,
+			}
+
+			if _, err := p.Run(); err != nil {
+				return err
+			}
+		}
+
+		client, err := shop.NewShopClient(cmd.Context(), cfg)
+		if err != nil {
+			return err
+		}
+
+		if err := applyConfigSnapshot(adminSdk.NewApiContext(cmd.Context()), client, snapshot); err != nil {
+			return err
+		}
+
+		logging.FromContext(cmd.Context()).Infof("The remote values of %s have been restored", file)
+
+		// the restored values are the new state of the shop, so they must not be reported as conflicts on the next push
+		restored, err := buildConfigSyncOperation(adminSdk.NewApiContext(cmd.Context()), client, cfg)
+		if err != nil {
+			return err
+		}
+
+		lock, err := readConfigLock()
+		if err != nil {
+			return err
+		}
+
+		lock.update(cfg.URL, restored, nil)
+
+		if err := writeConfigLock(lock); err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	projectConfigCmd.AddCommand(projectConfigRollbackCmd)
+	projectConfigRollbackCmd.PersistentFlags().Bool("auto-approve", false, "Skips the confirmation")
+}
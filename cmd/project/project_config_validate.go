@@ -0,0 +1,37 @@
+package project
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+var projectConfigValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validates the project config and its included files",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		problems, err := shop.ValidateConfig(projectConfigPath)
+		if err != nil {
+			return err
+		}
+
+		if len(problems) == 0 {
+			logging.FromContext(cmd.Context()).Infof("%s is valid", projectConfigPath)
+
+			return nil
+		}
+
+		for _, problem := range problems {
+			fmt.Println(problem.Error())
+		}
+
+		return fmt.Errorf("found %d problems in the project config", len(problems))
+	},
+}
+
+func init() {
+	projectConfigCmd.AddCommand(projectConfigValidateCmd)
+}
@@ -0,0 +1,12 @@
+package project
+
+import "github.com/spf13/cobra"
+
+var projectDatabaseCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the database of the Shopware project",
+}
+
+func init() {
+	projectRootCmd.AddCommand(projectDatabaseCmd)
+}
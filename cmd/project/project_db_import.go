@@ -0,0 +1,238 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"filippo.io/age"
+	"github.com/go-sql-driver/mysql"
+	"github.com/spf13/cobra"
+
+	"github.com/FriendsOfShopware/shopware-cli/internal/dbdump"
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+)
+
+var projectDatabaseImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Imports a database dump created by project dump",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		mysqlConfig, err := assembleConnectionURI(cmd)
+		if err != nil {
+			return err
+		}
+
+		dropDatabase, _ := cmd.Flags().GetBool("drop")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		identityFiles, _ := cmd.Flags().GetStringSlice("identity")
+		passphrase, _ := cmd.Flags().GetString("passphrase")
+
+		if passphrase == "" {
+			passphrase = os.Getenv("SHOPWARE_CLI_DUMP_PASSPHRASE")
+		}
+
+		identities, err := dbdump.ParseIdentities(identityFiles, passphrase)
+		if err != nil {
+			return err
+		}
+
+		if stat, err := os.Stat(args[0]); err == nil && stat.IsDir() {
+			return importDatabaseDirectory(ctx, mysqlConfig, args[0], dropDatabase, parallel, identities)
+		}
+
+		var input io.Reader
+		var inputSize int64
+
+		if args[0] == "-" {
+			input = os.Stdin
+		} else {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+
+			defer func() {
+				if err := file.Close(); err != nil {
+					logging.FromContext(ctx).Errorf("Import: %v", err)
+				}
+			}()
+
+			stat, err := file.Stat()
+			if err != nil {
+				return err
+			}
+
+			input = file
+			inputSize = stat.Size()
+		}
+
+		if err := prepareImportDatabase(ctx, mysqlConfig, dropDatabase); err != nil {
+			return err
+		}
+
+		db, err := sql.Open("mysql", mysqlConfig.FormatDSN())
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := db.Close(); err != nil {
+				logging.FromContext(ctx).Errorf("Import: %v", err)
+			}
+		}()
+
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := conn.Close(); err != nil {
+				logging.FromContext(ctx).Errorf("Import: %v", err)
+			}
+		}()
+
+		counter := dbdump.NewCountingReader(input)
+
+		reader, err := dbdump.NewReader(counter, identities...)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := reader.Close(); err != nil {
+				logging.FromContext(ctx).Errorf("Import: %v", err)
+			}
+		}()
+
+		start := time.Now()
+		stopProgress := reportImportProgress(ctx, counter, inputSize)
+
+		count, err := dbdump.Import(ctx, conn, reader)
+
+		stopProgress()
+
+		if err != nil {
+			return err
+		}
+
+		logging.FromContext(ctx).Infof("Imported %d statements into %s in %s", count, mysqlConfig.DBName, time.Since(start).Round(time.Second))
+
+		return nil
+	},
+}
+
+func importDatabaseDirectory(ctx context.Context, mysqlConfig *mysql.Config, dir string, dropDatabase bool, parallel int, identities []age.Identity) error {
+	manifest, err := dbdump.ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := prepareImportDatabase(ctx, mysqlConfig, dropDatabase); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("mysql", mysqlConfig.FormatDSN())
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			logging.FromContext(ctx).Errorf("Import: %v", err)
+		}
+	}()
+
+	start := time.Now()
+	total := len(manifest.Files())
+
+	var done atomic.Int32
+
+	err = dbdump.ImportDirectory(ctx, db, dir, parallel, identities, func(file string) {
+		logging.FromContext(ctx).Infof("Imported %s (%d/%d)", file, done.Add(1), total)
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Infof("Imported %d tables into %s in %s", len(manifest.Tables), mysqlConfig.DBName, time.Since(start).Round(time.Second))
+
+	return nil
+}
+
+func prepareImportDatabase(ctx context.Context, mysqlConfig *mysql.Config, dropDatabase bool) error {
+	serverConfig := mysqlConfig.Clone()
+	serverConfig.DBName = ""
+
+	db, err := sql.Open("mysql", serverConfig.FormatDSN())
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			logging.FromContext(ctx).Errorf("Import: %v", err)
+		}
+	}()
+
+	if dropDatabase {
+		logging.FromContext(ctx).Infof("Dropping database %s", mysqlConfig.DBName)
+
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", quoteIdentifier(mysqlConfig.DBName))); err != nil {
+			return err
+		}
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", quoteIdentifier(mysqlConfig.DBName)))
+
+	return err
+}
+
+// quoteIdentifier quotes the name for MySQL, backticks in the name are escaped by doubling them.
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func reportImportProgress(ctx context.Context, counter *dbdump.CountingReader, total int64) func() {
+	ticker := time.NewTicker(5 * time.Second)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				read := counter.Count()
+
+				if total > 0 {
+					logging.FromContext(ctx).Infof("Imported %.1f%% (%d/%d MB)", float64(read)/float64(total)*100, read/1024/1024, total/1024/1024)
+				} else {
+					logging.FromContext(ctx).Infof("Imported %d MB", read/1024/1024)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+func init() {
+	projectDatabaseCmd.AddCommand(projectDatabaseImportCmd)
+	addDatabaseConnectionFlags(projectDatabaseImportCmd)
+	projectDatabaseImportCmd.Flags().Bool("drop", false, "Drops and recreates the database before the import")
+	projectDatabaseImportCmd.Flags().StringSlice("identity", []string{}, "age identity file to decrypt an encrypted dump, can be passed multiple times")
+	projectDatabaseImportCmd.Flags().String("passphrase", "", "Passphrase to decrypt an encrypted dump (env: SHOPWARE_CLI_DUMP_PASSPHRASE)")
+	projectDatabaseImportCmd.Flags().Int("parallel", 4, "Amount of tables imported concurrently, only used for dumps in the directory format")
+}
@@ -0,0 +1,12 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	assert.Equal(t, "`shopware`", quoteIdentifier("shopware"))
+	assert.Equal(t, "`shop``; DROP DATABASE x; --`", quoteIdentifier("shop`; DROP DATABASE x; --"))
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/FriendsOfShopware/shopware-cli/extension"
 	"github.com/FriendsOfShopware/shopware-cli/logging"
-	"github.com/FriendsOfShopware/shopware-cli/shop"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"os"
@@ -22,7 +21,7 @@ var projectDebug = &cobra.Command{
 			return err
 		}
 
-		shopCfg, err := shop.ReadConfig(projectConfigPath, true)
+		shopCfg, err := readProjectConfig(true)
 		if err != nil {
 			return err
 		}
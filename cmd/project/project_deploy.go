@@ -0,0 +1,151 @@
+package project
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+var projectDeployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Installs or updates the Shopware project using the deployment config",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		ctx := cmd.Context()
+
+		d, err := newDeployment(cmd)
+		if err != nil {
+			return err
+		}
+
+		defer d.Close(ctx)
+
+		deploymentCfg := d.config
+
+		currentVersion, err := shop.GetShopwareVersion(d.projectRoot)
+		if err != nil {
+			return err
+		}
+
+		installed, err := d.isInstalled(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot determine if Shopware is installed: %w", err)
+		}
+
+		if err := d.hook(ctx, "pre", deploymentCfg.Hooks.Pre); err != nil {
+			return err
+		}
+
+		shopwareChanged := true
+		extensionsChanged := false
+
+		if installed {
+			previousVersion, err := d.deployedVersion(ctx)
+			if err != nil {
+				return err
+			}
+
+			shopwareChanged = previousVersion != currentVersion
+
+			if err := d.hook(ctx, "pre-update", deploymentCfg.Hooks.PreUpdate); err != nil {
+				return err
+			}
+
+			if shopwareChanged {
+				logging.FromContext(ctx).Infof("Updating Shopware from %s to %s", previousVersion, currentVersion)
+			} else {
+				logging.FromContext(ctx).Infof("Updating Shopware %s", currentVersion)
+			}
+
+			// extensions can ship migrations without a new Shopware version, so the migrations run on every update
+			if err := d.console(ctx, "system:update:prepare"); err != nil {
+				return err
+			}
+
+			if err := d.console(ctx, "system:update:finish"); err != nil {
+				return err
+			}
+
+			if err := d.console(ctx, "plugin:refresh"); err != nil {
+				return err
+			}
+		} else {
+			logging.FromContext(ctx).Infof("Installing Shopware %s", currentVersion)
+
+			if err := d.hook(ctx, "pre-install", deploymentCfg.Hooks.PreInstall); err != nil {
+				return err
+			}
+
+			shopLocale, _ := cmd.Flags().GetString("shop-locale")
+			shopCurrency, _ := cmd.Flags().GetString("shop-currency")
+
+			if err := d.console(ctx, "system:install", "--create-database", "--force", fmt.Sprintf("--shop-locale=%s", shopLocale), fmt.Sprintf("--shop-currency=%s", shopCurrency)); err != nil {
+				return err
+			}
+
+			if err := d.console(ctx, "plugin:refresh"); err != nil {
+				return err
+			}
+		}
+
+		if deploymentCfg.ExtensionManagement.Enabled {
+			plan, err := d.extensionPlan(ctx)
+			if err != nil {
+				return err
+			}
+
+			if err := d.applyExtensionPlan(ctx, plan); err != nil {
+				return err
+			}
+
+			extensionsChanged = len(plan) > 0
+		}
+
+		if err := d.runOneTimeTasks(ctx); err != nil {
+			return err
+		}
+
+		if deploymentCfg.Store.LicenseDomain != "" {
+			if err := d.console(ctx, "system:config:set", "core.store.licenseHost", deploymentCfg.Store.LicenseDomain); err != nil {
+				return err
+			}
+		}
+
+		if installed {
+			if err := d.hook(ctx, "post-update", deploymentCfg.Hooks.PostUpdate); err != nil {
+				return err
+			}
+		} else {
+			if err := d.hook(ctx, "post-install", deploymentCfg.Hooks.PostInstall); err != nil {
+				return err
+			}
+		}
+
+		if err := d.console(ctx, "system:config:set", deploymentVersionKey, currentVersion); err != nil {
+			return err
+		}
+
+		if deploymentCfg.Cache.AlwaysClear || shopwareChanged || extensionsChanged {
+			if err := d.console(ctx, "cache:clear"); err != nil {
+				return err
+			}
+		}
+
+		if err := d.hook(ctx, "post", deploymentCfg.Hooks.Post); err != nil {
+			return err
+		}
+
+		logging.FromContext(ctx).Infof("Deployment of Shopware %s finished", currentVersion)
+
+		return nil
+	},
+}
+
+func init() {
+	projectRootCmd.AddCommand(projectDeployCmd)
+	addDatabaseConnectionFlags(projectDeployCmd)
+	projectDeployCmd.Flags().String("shop-locale", "en-GB", "Default locale used for a fresh installation")
+	projectDeployCmd.Flags().String("shop-currency", "EUR", "Default currency used for a fresh installation")
+}
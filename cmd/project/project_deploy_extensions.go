@@ -0,0 +1,54 @@
+package project
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var projectDeployExtensionsCmd = &cobra.Command{
+	Use:   "extensions",
+	Short: "Reconciles the installed extensions with the extension management config",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		ctx := cmd.Context()
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		outputAsJson, _ := cmd.Flags().GetBool("json")
+
+		d, err := newDeployment(cmd)
+		if err != nil {
+			return err
+		}
+
+		defer d.Close(ctx)
+
+		if !d.config.ExtensionManagement.Enabled {
+			return fmt.Errorf("the extension management is not enabled in the deployment config")
+		}
+
+		// the plugin list must be refreshed first, so the plan contains newly added plugins
+		if !dryRun {
+			if err := d.console(ctx, "plugin:refresh"); err != nil {
+				return err
+			}
+		}
+
+		plan, err := d.extensionPlan(ctx)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			return plan.Render(os.Stdout, outputAsJson)
+		}
+
+		return d.applyExtensionPlan(ctx, plan)
+	},
+}
+
+func init() {
+	projectDeployCmd.AddCommand(projectDeployExtensionsCmd)
+	projectDeployExtensionsCmd.Flags().Bool("dry-run", false, "Only print the planned changes")
+	projectDeployExtensionsCmd.Flags().Bool("json", false, "Output the plan as json")
+}
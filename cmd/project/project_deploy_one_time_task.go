@@ -0,0 +1,150 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+)
+
+var projectDeployOneTimeTaskCmd = &cobra.Command{
+	Use:   "one-time-task",
+	Short: "Manage the execution history of the one-time tasks",
+}
+
+var projectDeployOneTimeTaskListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List all one-time tasks with their execution state",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		ctx := cmd.Context()
+
+		outputAsJson, _ := cmd.Flags().GetBool("json")
+
+		d, err := newDeployment(cmd)
+		if err != nil {
+			return err
+		}
+
+		defer d.Close(ctx)
+
+		executions, err := d.oneTimeTaskExecutions(ctx)
+		if err != nil {
+			return err
+		}
+
+		states := d.oneTimeTaskStates(executions)
+
+		if outputAsJson {
+			content, err := json.Marshal(states)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(string(content))
+
+			return nil
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetColWidth(100)
+		table.SetHeader([]string{"ID", "Status", "Started at", "Duration", "Exit code"})
+
+		for _, state := range states {
+			if state.Execution == nil {
+				table.Append([]string{state.Id, state.Status, "", "", ""})
+				continue
+			}
+
+			status := state.Status
+
+			if !state.Configured {
+				status += " (not configured)"
+			}
+
+			table.Append([]string{
+				state.Id,
+				status,
+				state.Execution.StartedAt.Local().Format(time.DateTime),
+				state.Execution.Duration.String(),
+				fmt.Sprintf("%d", state.Execution.ExitCode),
+			})
+		}
+
+		table.Render()
+
+		return nil
+	},
+}
+
+var projectDeployOneTimeTaskMarkDoneCmd = &cobra.Command{
+	Use:   "mark-done [id]",
+	Short: "Marks a one-time task as executed without running it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		d, err := newDeployment(cmd)
+		if err != nil {
+			return err
+		}
+
+		defer d.Close(ctx)
+
+		if !d.hasOneTimeTask(args[0]) {
+			return fmt.Errorf("%w: %s", errOneTimeTaskNotFound, args[0])
+		}
+
+		if err := d.saveOneTimeTaskExecution(ctx, oneTimeTaskExecution{Id: args[0], StartedAt: time.Now(), Output: "marked as done manually"}); err != nil {
+			return err
+		}
+
+		logging.FromContext(ctx).Infof("Marked one-time task %s as done", args[0])
+
+		return nil
+	},
+}
+
+var projectDeployOneTimeTaskUnmarkCmd = &cobra.Command{
+	Use:   "unmark [id]",
+	Short: "Removes the execution of a one-time task, so it will be executed again on the next deployment",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		d, err := newDeployment(cmd)
+		if err != nil {
+			return err
+		}
+
+		defer d.Close(ctx)
+
+		removed, err := d.removeOneTimeTaskExecution(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		if !removed {
+			logging.FromContext(ctx).Infof("One-time task %s has not been executed yet", args[0])
+
+			return nil
+		}
+
+		logging.FromContext(ctx).Infof("One-time task %s will be executed on the next deployment", args[0])
+
+		return nil
+	},
+}
+
+func init() {
+	projectDeployCmd.AddCommand(projectDeployOneTimeTaskCmd)
+	projectDeployOneTimeTaskCmd.AddCommand(projectDeployOneTimeTaskListCmd)
+	projectDeployOneTimeTaskCmd.AddCommand(projectDeployOneTimeTaskMarkDoneCmd)
+	projectDeployOneTimeTaskCmd.AddCommand(projectDeployOneTimeTaskUnmarkCmd)
+	projectDeployOneTimeTaskListCmd.Flags().Bool("json", false, "Output as json")
+}
@@ -1,16 +1,15 @@
 package project
 
 import (
-	"compress/gzip"
 	"context"
 	"database/sql"
 	"fmt"
 	"github.com/FriendsOfShopware/shopware-cli/extension"
+	"github.com/FriendsOfShopware/shopware-cli/internal/dbdump"
 	"github.com/doutorfinancas/go-mad/core"
 	"github.com/doutorfinancas/go-mad/database"
 	"github.com/doutorfinancas/go-mad/generator"
 	"github.com/go-sql-driver/mysql"
-	"github.com/klauspost/compress/zstd"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"io"
@@ -23,8 +22,10 @@ import (
 	"github.com/FriendsOfShopware/shopware-cli/shop"
 )
 
-const CompressionGzip = "gzip"
-const CompressionZstd = "zstd"
+const (
+	DumpFormatFile      = "file"
+	DumpFormatDirectory = "directory"
+)
 
 var projectDatabaseDumpCmd = &cobra.Command{
 	Use:   "dump",
@@ -41,133 +42,101 @@ var projectDatabaseDumpCmd = &cobra.Command{
 		skipLockTables, _ := cmd.Flags().GetBool("skip-lock-tables")
 		anonymize, _ := cmd.Flags().GetBool("anonymize")
 		compression, _ := cmd.Flags().GetString("compression")
+		format, _ := cmd.Flags().GetString("format")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		subset, _ := cmd.Flags().GetBool("subset")
+		profile, _ := cmd.Flags().GetString("profile")
+		anonymizeKey, _ := cmd.Flags().GetString("anonymize-key")
+		target, _ := cmd.Flags().GetString("target")
+		recipients, _ := cmd.Flags().GetStringSlice("recipient")
+		passphrase, _ := cmd.Flags().GetString("passphrase")
+
+		if passphrase == "" {
+			passphrase = os.Getenv("SHOPWARE_CLI_DUMP_PASSPHRASE")
+		}
 
-		db, err := sql.Open("mysql", mysqlConfig.FormatDSN())
-		if err != nil {
-			return err
+		if !dbdump.IsValidTarget(target) {
+			return fmt.Errorf("unsupported target %q, supported are %s, %s and %s", target, dbdump.TargetMariaDB, dbdump.TargetMySQL57, dbdump.TargetMySQL8)
 		}
 
-		service := generator.NewService()
-		var opt []database.Option
-		opt = append(opt, database.OptionValue("hex-encode", "1"))
-		opt = append(opt, database.OptionValue("set-charset", "utf8mb4"))
-		opt = append(opt, database.OptionValue("dump-trigger", ""))
-		opt = append(opt, database.OptionValue("skip-definer", ""))
-		opt = append(opt, database.OptionValue("trigger-delimiter", "//"))
+		if anonymizeKey == "" {
+			anonymizeKey = os.Getenv("SHOPWARE_CLI_ANONYMIZE_KEY")
+		}
 
-		if skipLockTables {
-			opt = append(opt, database.OptionValue("skip-lock-tables", "1"))
+		var projectCfg *shop.Config
+		if projectCfg, err = readProjectConfig(true); err != nil {
+			return err
 		}
 
-		logger, _ := zap.NewProduction()
-		dumper, err := database.NewMySQLDumper(db, logger, service, opt...)
+		pConf, subset, err := buildDumpRules(projectCfg, profile, clean, anonymize, subset)
 		if err != nil {
 			return err
 		}
 
-		pConf := core.Rules{Ignore: []string{}, NoData: []string{}, Where: map[string]string{}, Rewrite: map[string]core.Rewrite{}}
-
-		if clean {
-			pConf.NoData = append(pConf.NoData,
-				"cart",
-				"customer_recovery",
-				"dead_message",
-				"enqueue",
-				"messenger_messages",
-				"import_export_log",
-				"increment",
-				"elasticsearch_index_task",
-				"log_entry",
-				"message_queue_stats",
-				"notification",
-				"payment_token",
-				"refresh_token",
-				"version",
-				"version_commit",
-				"version_commit_data",
-				"webhook_event_log",
-			)
-		}
-
-		if anonymize {
-			pConf.Rewrite = map[string]core.Rewrite{
-				"customer": map[string]string{
-					"first_name":     "faker.Person.FirstName()",
-					"last_name":      "faker.Person.LastName()",
-					"company":        "faker.Person.Name()",
-					"title":          "faker.Person.Name()",
-					"email":          "faker.Internet.Email()",
-					"remote_address": "faker.Internet.Ipv4()",
-				},
-				"customer_address": map[string]string{
-					"first_name":   "faker.Person.FirstName()",
-					"last_name":    "faker.Person.LastName()",
-					"company":      "faker.Person.Name()",
-					"title":        "faker.Person.Name()",
-					"street":       "faker.Address.StreetAddress()",
-					"zipcode":      "faker.Address.PostCode()",
-					"city":         "faker.Address.City()",
-					"phone_number": "faker.Phone.Number()",
-				},
-				"log_entry": map[string]string{
-					"provider": "",
-				},
-				"newsletter_recipient": map[string]string{
-					"email":      "faker.Internet.Email()",
-					"first_name": "faker.Person.FirstName()",
-					"last_name":  "faker.Person.LastName()",
-					"city":       "faker.Address.City()",
-				},
-				"order_address": map[string]string{
-					"first_name":   "faker.Person.FirstName()",
-					"last_name":    "faker.Person.LastName()",
-					"company":      "faker.Person.Name()",
-					"title":        "faker.Person.Name()",
-					"street":       "faker.Address.StreetAddress()",
-					"zipcode":      "faker.Address.PostCode()",
-					"city":         "faker.Address.City()",
-					"phone_number": "faker.Phone.Number()",
-				},
-				"order_customer": map[string]string{
-					"first_name":     "faker.Person.FirstName()",
-					"last_name":      "faker.Person.LastName()",
-					"company":        "faker.Person.Name()",
-					"title":          "faker.Person.Name()",
-					"email":          "faker.Internet.Email()",
-					"remote_address": "faker.Internet.Ipv4()",
-				},
-				"product_review": map[string]string{
-					"email": "faker.Internet.Email()",
-				},
-				"user": map[string]string{
-					"username":   "faker.Person.Name()",
-					"first_name": "faker.Person.FirstName()",
-					"last_name":  "faker.Person.LastName()",
-					"email":      "faker.Internet.Email()",
-				},
+		if len(recipients) == 0 && passphrase == "" {
+			if encryption := dumpEncryption(projectCfg, profile); encryption != nil {
+				if err := shop.ResolveSecretReferences(encryption); err != nil {
+					return err
+				}
+
+				recipients, passphrase = encryption.Recipients, encryption.Passphrase
 			}
 		}
 
-		var projectCfg *shop.Config
-		if projectCfg, err = shop.ReadConfig(projectConfigPath, true); err != nil {
+		ageRecipients, err := dbdump.ParseRecipients(recipients, passphrase)
+		if err != nil {
 			return err
 		}
 
-		if projectCfg != nil && projectCfg.ConfigDump != nil {
-			pConf.NoData = append(pConf.NoData, projectCfg.ConfigDump.NoData...)
-			pConf.Ignore = append(pConf.Ignore, projectCfg.ConfigDump.Ignore...)
-			for table, rewrites := range projectCfg.ConfigDump.Rewrite {
-				_, ok := pConf.Rewrite[table]
+		if anonymizeKey != "" {
+			pConf.Rewrite = dbdump.DeterministicRewrites(pConf.Rewrite, anonymizeKey)
+		}
 
-				if !ok {
-					pConf.Rewrite[table] = rewrites
-				} else {
-					for k, v := range rewrites {
-						pConf.Rewrite[table][k] = v
-					}
-				}
+		if subset && len(pConf.Where) > 0 {
+			if pConf.Where, err = subsetWhere(cmd.Context(), mysqlConfig, pConf.Where); err != nil {
+				return err
 			}
-			pConf.Where = projectCfg.ConfigDump.Where
+		}
+
+		if format == DumpFormatDirectory {
+			if !cmd.Flags().Changed("output") {
+				output = "dump"
+			}
+
+			if output == "-" {
+				return fmt.Errorf("the directory format cannot be written to stdout")
+			}
+
+			manifest, err := dbdump.DumpDirectory(cmd.Context(), mysqlConfig, output, dbdump.DirectoryDumpOptions{
+				Rules:          pConf,
+				Compression:    compression,
+				Workers:        parallel,
+				SkipLockTables: skipLockTables,
+				Target:         target,
+				Recipients:     ageRecipients,
+			})
+			if err != nil {
+				return err
+			}
+
+			logging.FromContext(cmd.Context()).Infof("Successfully dumped %d tables into %s", len(manifest.Tables), output)
+
+			return nil
+		}
+
+		if format != DumpFormatFile {
+			return fmt.Errorf("unsupported dump format %q", format)
+		}
+
+		db, err := sql.Open("mysql", mysqlConfig.FormatDSN())
+		if err != nil {
+			return err
+		}
+
+		logger, _ := zap.NewProduction()
+		dumper, err := database.NewMySQLDumper(db, logger, generator.NewService(), dbdump.DumperOptions(skipLockTables, true)...)
+		if err != nil {
+			return err
 		}
 
 		dumper.SetSelectMap(pConf.RewriteToMap())
@@ -180,32 +149,38 @@ var projectDatabaseDumpCmd = &cobra.Command{
 		if output == "-" {
 			w = os.Stdout
 		} else {
-			if compression == CompressionGzip {
-				output += ".gz"
-			}
+			output += dbdump.FileExtension(compression)
 
-			if compression == CompressionZstd {
-				output += ".zst"
+			if len(ageRecipients) > 0 {
+				output += dbdump.EncryptedFileExtension
 			}
 
-			if w, err = os.Create(output); err != nil {
+			file, err := os.Create(output)
+			if err != nil {
 				return err
 			}
+
+			defer file.Close()
+
+			w = file
 		}
 
-		if compression == CompressionGzip {
-			w = gzip.NewWriter(w)
+		encryptedWriter, err := dbdump.NewEncryptingWriter(w, ageRecipients)
+		if err != nil {
+			return err
 		}
 
-		if compression == CompressionZstd {
-			w, err = zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		compressedWriter, err := dbdump.NewWriter(encryptedWriter, compression)
+		if err != nil {
+			return err
+		}
 
-			if err != nil {
-				return err
-			}
+		targetWriter, err := dbdump.NewTargetWriter(compressedWriter, target)
+		if err != nil {
+			return err
 		}
 
-		if err = dumper.Dump(w); err != nil {
+		if err = dumper.Dump(targetWriter); err != nil {
 			if strings.Contains(err.Error(), "the RELOAD or FLUSH_TABLES privilege") {
 				return fmt.Errorf("%s, you maybe want to disable locking with --skip-lock-tables", err.Error())
 			}
@@ -213,10 +188,16 @@ var projectDatabaseDumpCmd = &cobra.Command{
 			return err
 		}
 
-		if compression == CompressionGzip {
-			if err = w.(*gzip.Writer).Close(); err != nil {
-				return err
-			}
+		if err = targetWriter.Close(); err != nil {
+			return err
+		}
+
+		if err = compressedWriter.Close(); err != nil {
+			return err
+		}
+
+		if err = encryptedWriter.Close(); err != nil {
+			return err
 		}
 
 		logging.FromContext(cmd.Context()).Infof("Successfully created the dump %s", output)
@@ -225,6 +206,191 @@ var projectDatabaseDumpCmd = &cobra.Command{
 	},
 }
 
+// dumpCleanTables are the tables without relevant content for a dump, used by --clean.
+var dumpCleanTables = []string{
+	"cart",
+	"customer_recovery",
+	"dead_message",
+	"enqueue",
+	"messenger_messages",
+	"import_export_log",
+	"increment",
+	"elasticsearch_index_task",
+	"log_entry",
+	"message_queue_stats",
+	"notification",
+	"payment_token",
+	"refresh_token",
+	"version",
+	"version_commit",
+	"version_commit_data",
+	"webhook_event_log",
+}
+
+// dumpAnonymizeRewrites returns the rewrites of known user data tables, used by --anonymize.
+func dumpAnonymizeRewrites() map[string]core.Rewrite {
+	return map[string]core.Rewrite{
+		"customer": map[string]string{
+			"first_name":     "faker.Person.FirstName()",
+			"last_name":      "faker.Person.LastName()",
+			"company":        "faker.Person.Name()",
+			"title":          "faker.Person.Name()",
+			"email":          "faker.Internet.Email()",
+			"remote_address": "faker.Internet.Ipv4()",
+		},
+		"customer_address": map[string]string{
+			"first_name":   "faker.Person.FirstName()",
+			"last_name":    "faker.Person.LastName()",
+			"company":      "faker.Person.Name()",
+			"title":        "faker.Person.Name()",
+			"street":       "faker.Address.StreetAddress()",
+			"zipcode":      "faker.Address.PostCode()",
+			"city":         "faker.Address.City()",
+			"phone_number": "faker.Phone.Number()",
+		},
+		"log_entry": map[string]string{
+			"provider": "",
+		},
+		"newsletter_recipient": map[string]string{
+			"email":      "faker.Internet.Email()",
+			"first_name": "faker.Person.FirstName()",
+			"last_name":  "faker.Person.LastName()",
+			"city":       "faker.Address.City()",
+		},
+		"order_address": map[string]string{
+			"first_name":   "faker.Person.FirstName()",
+			"last_name":    "faker.Person.LastName()",
+			"company":      "faker.Person.Name()",
+			"title":        "faker.Person.Name()",
+			"street":       "faker.Address.StreetAddress()",
+			"zipcode":      "faker.Address.PostCode()",
+			"city":         "faker.Address.City()",
+			"phone_number": "faker.Phone.Number()",
+		},
+		"order_customer": map[string]string{
+			"first_name":     "faker.Person.FirstName()",
+			"last_name":      "faker.Person.LastName()",
+			"company":        "faker.Person.Name()",
+			"title":          "faker.Person.Name()",
+			"email":          "faker.Internet.Email()",
+			"remote_address": "faker.Internet.Ipv4()",
+		},
+		"product_review": map[string]string{
+			"email": "faker.Internet.Email()",
+		},
+		"user": map[string]string{
+			"username":   "faker.Internet.User()",
+			"first_name": "faker.Person.FirstName()",
+			"last_name":  "faker.Person.LastName()",
+			"email":      "faker.Internet.Email()",
+		},
+	}
+}
+
+func buildDumpRules(projectCfg *shop.Config, profileName string, clean, anonymize, subset bool) (core.Rules, bool, error) {
+	rules := core.Rules{Ignore: []string{}, NoData: []string{}, Where: map[string]string{}, Rewrite: map[string]core.Rewrite{}}
+
+	var dumpCfg *shop.ConfigDump
+	if projectCfg != nil {
+		dumpCfg = projectCfg.ConfigDump
+	}
+
+	var profile *shop.ConfigDumpProfile
+
+	if profileName != "" {
+		if dumpCfg == nil {
+			return rules, false, fmt.Errorf("dump profile %q is not configured", profileName)
+		}
+
+		p, ok := dumpCfg.Profiles[profileName]
+		if !ok {
+			return rules, false, fmt.Errorf("dump profile %q is not configured", profileName)
+		}
+
+		profile = &p
+		clean = clean || profile.Clean
+		anonymize = anonymize || profile.Anonymize
+		subset = subset || profile.Subset
+	}
+
+	if clean {
+		rules.NoData = append(rules.NoData, dumpCleanTables...)
+	}
+
+	if anonymize {
+		rules.Rewrite = dumpAnonymizeRewrites()
+	}
+
+	// a profile contains all of its rules, only the presets of --clean and --anonymize are inherited on request
+	if profile != nil {
+		mergeDumpRules(&rules, profile.Rewrite, profile.NoData, profile.Ignore, profile.Where)
+	} else if dumpCfg != nil {
+		mergeDumpRules(&rules, dumpCfg.Rewrite, dumpCfg.NoData, dumpCfg.Ignore, dumpCfg.Where)
+		subset = subset || dumpCfg.Subset
+	}
+
+	return rules, subset, nil
+}
+
+// dumpEncryption returns the encryption of the profile, or of the dump block when the profile has none.
+func dumpEncryption(projectCfg *shop.Config, profileName string) *shop.ConfigDumpEncryption {
+	if projectCfg == nil || projectCfg.ConfigDump == nil {
+		return nil
+	}
+
+	if profile, ok := projectCfg.ConfigDump.Profiles[profileName]; ok && profile.Encryption != nil {
+		return profile.Encryption
+	}
+
+	return projectCfg.ConfigDump.Encryption
+}
+
+func mergeDumpRules(rules *core.Rules, rewrite map[string]core.Rewrite, noData, ignore []string, where map[string]string) {
+	rules.NoData = append(rules.NoData, noData...)
+	rules.Ignore = append(rules.Ignore, ignore...)
+
+	for table, rewrites := range rewrite {
+		if _, ok := rules.Rewrite[table]; !ok {
+			rules.Rewrite[table] = core.Rewrite{}
+		}
+
+		for k, v := range rewrites {
+			rules.Rewrite[table][k] = v
+		}
+	}
+
+	for table, condition := range where {
+		rules.Where[table] = condition
+	}
+}
+
+func subsetWhere(ctx context.Context, mysqlConfig *mysql.Config, roots map[string]string) (map[string]string, error) {
+	db, err := sql.Open("mysql", mysqlConfig.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			logging.FromContext(ctx).Errorf("subsetWhere: %v", err)
+		}
+	}()
+
+	keys, err := dbdump.LoadForeignKeys(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("could not read foreign keys: %w", err)
+	}
+
+	where, err := dbdump.SubsetWhere(ctx, roots, keys, dbdump.QuerySubsetValues(db))
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Infof("Subsetting %d tables starting from %d filtered tables", len(where), len(roots))
+
+	return where, nil
+}
+
 func assembleConnectionURI(cmd *cobra.Command) (*mysql.Config, error) {
 	cfg := &mysql.Config{
 		Loc:                  time.UTC,
@@ -274,6 +440,14 @@ func assembleConnectionURI(cmd *cobra.Command) (*mysql.Config, error) {
 	return cfg, nil
 }
 
+func addDatabaseConnectionFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("host", "", "hostname")
+	cmd.PersistentFlags().String("database", "", "database name")
+	cmd.PersistentFlags().StringP("username", "u", "", "mysql user")
+	cmd.PersistentFlags().StringP("password", "p", "", "mysql password")
+	cmd.PersistentFlags().String("port", "", "mysql port")
+}
+
 func loadDatabaseURLIntoConnection(ctx context.Context, projectRoot string, cfg *mysql.Config) error {
 	if err := extension.LoadSymfonyEnvFile(projectRoot); err != nil {
 		return err
@@ -321,16 +495,20 @@ func loadDatabaseURLIntoConnection(ctx context.Context, projectRoot string, cfg
 
 func init() {
 	projectRootCmd.AddCommand(projectDatabaseDumpCmd)
-	projectDatabaseDumpCmd.Flags().String("host", "", "hostname")
-	projectDatabaseDumpCmd.Flags().String("database", "", "database name")
-	projectDatabaseDumpCmd.Flags().StringP("username", "u", "", "mysql user")
-	projectDatabaseDumpCmd.Flags().StringP("password", "p", "", "mysql password")
-	projectDatabaseDumpCmd.Flags().String("port", "", "mysql port")
+	addDatabaseConnectionFlags(projectDatabaseDumpCmd)
 
 	projectDatabaseDumpCmd.Flags().String("output", "dump.sql", "file or - (for stdout)")
 	projectDatabaseDumpCmd.Flags().Bool("clean", false, "Ignores cart, enqueue, message_queue_stats")
 	projectDatabaseDumpCmd.Flags().Bool("skip-lock-tables", false, "Skips locking the tables")
 	projectDatabaseDumpCmd.Flags().Bool("anonymize", false, "Anonymize customer data")
+	projectDatabaseDumpCmd.Flags().String("anonymize-key", "", "Derive the anonymized values from a keyed hash of the original values, so they are the same across tables and dumps (env: SHOPWARE_CLI_ANONYMIZE_KEY)")
 	projectDatabaseDumpCmd.Flags().String("compression", "", "Compress the dump (gzip, zstd)")
+	projectDatabaseDumpCmd.Flags().String("format", DumpFormatFile, "Format of the dump (file, directory)")
+	projectDatabaseDumpCmd.Flags().Int("parallel", 4, "Amount of tables dumped concurrently, only used with --format=directory")
+	projectDatabaseDumpCmd.Flags().StringSlice("recipient", []string{}, "Encrypt the dump for an age recipient (age1...) or a recipients file, can be passed multiple times")
+	projectDatabaseDumpCmd.Flags().String("passphrase", "", "Encrypt the dump with a passphrase (env: SHOPWARE_CLI_DUMP_PASSPHRASE)")
+	projectDatabaseDumpCmd.Flags().String("target", "", "Rewrite the DDL statements for the server flavour the dump is imported into (mariadb, mysql57, mysql8)")
+	projectDatabaseDumpCmd.Flags().String("profile", "", "Use the rules of a named profile of dump.profiles")
+	projectDatabaseDumpCmd.Flags().Bool("subset", false, "Only export rows depending on the rows matched by the where conditions")
 	projectDatabaseDumpCmd.Flags().Bool("zstd", false, "Zstd the whole dump")
 }
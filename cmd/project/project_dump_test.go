@@ -0,0 +1,81 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/doutorfinancas/go-mad/core"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FriendsOfShopware/shopware-cli/shop"
+)
+
+func TestBuildDumpRules(t *testing.T) {
+	cfg := &shop.Config{
+		ConfigDump: &shop.ConfigDump{
+			NoData: []string{"log"},
+			Where:  map[string]string{"customer": "id > 5"},
+			Rewrite: map[string]core.Rewrite{
+				"customer": {"email": "'global'"},
+			},
+			Profiles: map[string]shop.ConfigDumpProfile{
+				"support": {
+					Clean:     true,
+					Anonymize: true,
+					Subset:    true,
+					Ignore:    []string{"product_review"},
+					Where:     map[string]string{"customer": "created_at > '2024-01-01'"},
+					Rewrite: map[string]core.Rewrite{
+						"customer": {"email": "'support'"},
+					},
+				},
+				"full-backup": {},
+			},
+		},
+	}
+
+	t.Run("without profile", func(t *testing.T) {
+		rules, subset, err := buildDumpRules(cfg, "", false, false, false)
+
+		assert.NoError(t, err)
+		assert.False(t, subset)
+		assert.Equal(t, []string{"log"}, rules.NoData)
+		assert.Equal(t, map[string]string{"customer": "id > 5"}, rules.Where)
+		assert.Equal(t, map[string]core.Rewrite{"customer": {"email": "'global'"}}, rules.Rewrite)
+	})
+
+	t.Run("profile inherits presets but not the rules of the dump block", func(t *testing.T) {
+		rules, subset, err := buildDumpRules(cfg, "support", false, false, false)
+
+		assert.NoError(t, err)
+		assert.True(t, subset)
+		assert.Equal(t, dumpCleanTables, rules.NoData)
+		assert.Equal(t, []string{"product_review"}, rules.Ignore)
+		assert.Equal(t, map[string]string{"customer": "created_at > '2024-01-01'"}, rules.Where)
+		assert.Equal(t, "'support'", rules.Rewrite["customer"]["email"])
+		assert.Equal(t, "faker.Person.FirstName()", rules.Rewrite["customer"]["first_name"])
+		assert.Equal(t, "'global'", cfg.ConfigDump.Rewrite["customer"]["email"], "config must not be modified")
+	})
+
+	t.Run("flags are kept", func(t *testing.T) {
+		rules, _, err := buildDumpRules(cfg, "full-backup", true, false, false)
+
+		assert.NoError(t, err)
+		assert.Contains(t, rules.NoData, "cart")
+		assert.NotContains(t, rules.NoData, "log")
+		assert.Empty(t, rules.Ignore)
+		assert.Empty(t, rules.Where)
+		assert.Empty(t, rules.Rewrite)
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		_, _, err := buildDumpRules(cfg, "unknown", false, false, false)
+
+		assert.ErrorContains(t, err, `dump profile "unknown" is not configured`)
+	})
+
+	t.Run("profile without config", func(t *testing.T) {
+		_, _, err := buildDumpRules(nil, "dev", false, false, false)
+
+		assert.Error(t, err)
+	})
+}
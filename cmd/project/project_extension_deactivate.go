@@ -18,7 +18,7 @@ var projectExtensionDeactivateCmd = &cobra.Command{
 		var cfg *shop.Config
 		var err error
 
-		if cfg, err = shop.ReadConfig(projectConfigPath, false); err != nil {
+		if cfg, err = readProjectConfig(false); err != nil {
 			return err
 		}
 
@@ -119,7 +119,7 @@ var projectExtensionUploadCmd = &cobra.Command{
 			}
 		}
 
-		if cfg, err = shop.ReadConfig(projectConfigPath, false); err != nil {
+		if cfg, err = readProjectConfig(false); err != nil {
 			return err
 		}
 
@@ -0,0 +1,42 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadProjectConfig(t *testing.T) {
+	previousPath, previousEnvironment := projectConfigPath, projectEnvironment
+
+	defer func() {
+		projectConfigPath, projectEnvironment = previousPath, previousEnvironment
+	}()
+
+	projectConfigPath = filepath.Join(t.TempDir(), ".shopware-project.yml")
+
+	assert.NoError(t, os.WriteFile(projectConfigPath, []byte(`url: https://example.com
+environments:
+  staging:
+    url: https://staging.example.com
+`), os.ModePerm))
+
+	projectEnvironment = ""
+
+	cfg, err := readProjectConfig(false)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", cfg.URL)
+
+	projectEnvironment = "staging"
+
+	cfg, err = readProjectConfig(false)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://staging.example.com", cfg.URL)
+
+	projectEnvironment = "production"
+
+	_, err = readProjectConfig(false)
+	assert.ErrorContains(t, err, `environment "production" is not configured`)
+}
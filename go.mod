@@ -4,6 +4,7 @@ go 1.23
 
 require (
 	dario.cat/mergo v1.0.1
+	filippo.io/age v1.2.1
 	github.com/NYTimes/gziphandler v1.1.1
 	github.com/bep/godartsass/v2 v2.3.2
 	github.com/caarlos0/env/v9 v9.0.0
@@ -31,7 +32,10 @@ require (
 	gopkg.in/yaml.v3 v3.0.1
 )
 
-require github.com/otiai10/mint v1.6.3 // indirect
+require (
+	github.com/otiai10/mint v1.6.3 // indirect
+	golang.org/x/crypto v0.32.0 // indirect
+)
 
 replace github.com/doutorfinancas/go-mad v0.0.0-20240205120830-463c1e9760f0 => github.com/shyim/go-mad v0.0.0-20241125132504-2377d2341711
 
@@ -43,15 +47,15 @@ require (
 	github.com/chzyer/readline v1.5.1 // indirect
 	github.com/cli/safeexec v1.0.1 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/go-sql-driver/mysql v1.8.1 // indirect
-	github.com/gobwas/glob v0.2.3 // indirect
+	github.com/go-sql-driver/mysql v1.8.1
+	github.com/gobwas/glob v0.2.3
 	github.com/gorilla/css v1.0.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/jaswdr/faker v1.19.1 // indirect
 	github.com/klauspost/compress v1.17.11
 	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/mattn/go-runewidth v0.0.16 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/tidwall/gjson v1.18.0 // indirect
@@ -61,7 +65,7 @@ require (
 	go.uber.org/multierr v1.11.0 // indirect
 	golang.org/x/net v0.34.0
 	golang.org/x/oauth2 v0.24.0 // indirect
-	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sync v0.10.0
 	golang.org/x/sys v0.29.0 // indirect
 	google.golang.org/protobuf v1.35.2 // indirect
 )
@@ -0,0 +1,91 @@
+package dbdump
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/doutorfinancas/go-mad/core"
+)
+
+var (
+	anonymizeFirstNames = []string{"Anna", "Ben", "Clara", "David", "Emma", "Felix", "Greta", "Hannah", "Ida", "Jonas", "Karl", "Lena", "Marie", "Noah", "Olivia", "Paul", "Quentin", "Rosa", "Sophie", "Tom", "Ute", "Victor", "Wilma", "Xaver", "Yara", "Zoe"}
+	anonymizeLastNames  = []string{"Bauer", "Becker", "Fischer", "Hoffmann", "Koch", "Klein", "Meyer", "Müller", "Neumann", "Richter", "Schäfer", "Schmidt", "Schneider", "Schulz", "Schwarz", "Wagner", "Weber", "Wolf", "Zimmermann", "Braun"}
+	anonymizeStreets    = []string{"Main Street", "Church Street", "High Street", "Park Avenue", "Station Road", "Mill Lane", "Garden Way", "Lake View", "Market Place", "School Road"}
+	anonymizeCities     = []string{"Springfield", "Riverside", "Fairview", "Greenville", "Bristol", "Clinton", "Franklin", "Georgetown", "Madison", "Salem"}
+)
+
+// DeterministicRewrites replaces the supported faker expressions of the rewrites with SQL expressions derived from a keyed hash of the original value.
+// The same value is rewritten to the same fake value in every table and dump, as long as the same key is used.
+func DeterministicRewrites(rewrites map[string]core.Rewrite, key string) map[string]core.Rewrite {
+	result := make(map[string]core.Rewrite, len(rewrites))
+
+	for table, columns := range rewrites {
+		rewrite := make(core.Rewrite, len(columns))
+
+		for column, expression := range columns {
+			rewrite[column] = DeterministicRewrite(expression, column, key)
+		}
+
+		result[table] = rewrite
+	}
+
+	return result
+}
+
+// DeterministicRewrite returns the SQL expression for a faker expression, unsupported expressions are returned unchanged.
+func DeterministicRewrite(expression, column, key string) string {
+	hash := fmt.Sprintf("SHA2(CONCAT(%s, '|', `%s`), 256)", quoteString(key), column)
+
+	var fake string
+
+	switch strings.ReplaceAll(expression, " ", "") {
+	case "faker.Person.FirstName()":
+		fake = pickFromList(hash, 1, anonymizeFirstNames)
+	case "faker.Person.LastName()":
+		fake = pickFromList(hash, 1, anonymizeLastNames)
+	case "faker.Person.Name()":
+		// only a few hundred names are possible, use it for columns without an unique index
+		fake = fmt.Sprintf("CONCAT(%s, ' ', %s)", pickFromList(hash, 1, anonymizeFirstNames), pickFromList(hash, 9, anonymizeLastNames))
+	case "faker.Internet.Email()":
+		// emails are compared case-insensitive, 64 bits of the hash keep them unique
+		hash = fmt.Sprintf("SHA2(CONCAT(%s, '|', LOWER(TRIM(`%s`))), 256)", quoteString(key), column)
+		fake = fmt.Sprintf("CONCAT('user-', SUBSTRING(%s, 1, 16), '@example.com')", hash)
+	case "faker.Internet.User()":
+		// user names have an unique index, 64 bits of the hash keep them unique
+		fake = fmt.Sprintf("CONCAT('user-', SUBSTRING(%s, 1, 16))", hash)
+	case "faker.Internet.Ipv4()":
+		fake = fmt.Sprintf("CONCAT_WS('.', 10, %s, %s, %s)", hashNumber(hash, 1, 2), hashNumber(hash, 3, 2), hashNumber(hash, 5, 2))
+	case "faker.Address.StreetAddress()":
+		fake = fmt.Sprintf("CONCAT(%s, ' ', 1 + %s %% 200)", pickFromList(hash, 1, anonymizeStreets), hashNumber(hash, 9, 8))
+	case "faker.Address.PostCode()":
+		fake = fmt.Sprintf("LPAD(%s %% 100000, 5, '0')", hashNumber(hash, 1, 8))
+	case "faker.Address.City()":
+		fake = pickFromList(hash, 1, anonymizeCities)
+	case "faker.Phone.Number()":
+		fake = fmt.Sprintf("CONCAT('+49 ', LPAD(%s %% 10000000000, 10, '0'))", hashNumber(hash, 1, 12))
+	default:
+		return expression
+	}
+
+	return fmt.Sprintf("IF(`%s` IS NULL, NULL, %s)", column, fake)
+}
+
+func hashNumber(hash string, offset, length int) string {
+	return fmt.Sprintf("CONV(SUBSTRING(%s, %d, %d), 16, 10)", hash, offset, length)
+}
+
+func pickFromList(hash string, offset int, values []string) string {
+	quoted := make([]string, 0, len(values))
+
+	for _, value := range values {
+		quoted = append(quoted, quoteString(value))
+	}
+
+	return fmt.Sprintf("ELT(1 + %s %% %d, %s)", hashNumber(hash, offset, 8), len(values), strings.Join(quoted, ", "))
+}
+
+func quoteString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
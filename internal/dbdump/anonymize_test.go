@@ -0,0 +1,39 @@
+package dbdump
+
+import (
+	"testing"
+
+	"github.com/doutorfinancas/go-mad/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeterministicRewrite(t *testing.T) {
+	t.Run("email", func(t *testing.T) {
+		assert.Equal(t, "IF(`email` IS NULL, NULL, CONCAT('user-', SUBSTRING(SHA2(CONCAT('secret', '|', LOWER(TRIM(`email`))), 256), 1, 16), '@example.com'))", DeterministicRewrite("faker.Internet.Email()", "email", "secret"))
+	})
+
+	t.Run("user name", func(t *testing.T) {
+		assert.Equal(t, "IF(`username` IS NULL, NULL, CONCAT('user-', SUBSTRING(SHA2(CONCAT('secret', '|', `username`), 256), 1, 16)))", DeterministicRewrite("faker.Internet.User()", "username", "secret"))
+	})
+
+	t.Run("key is quoted", func(t *testing.T) {
+		assert.Contains(t, DeterministicRewrite("faker.Address.City()", "city", `it's\`), `SHA2(CONCAT('it''s\\', '|', `+"`city`"+`), 256)`)
+	})
+
+	t.Run("unsupported expressions are kept", func(t *testing.T) {
+		assert.Equal(t, "faker.Lorem.Word()", DeterministicRewrite("faker.Lorem.Word()", "title", "secret"))
+		assert.Equal(t, "", DeterministicRewrite("", "provider", "secret"))
+	})
+}
+
+func TestDeterministicRewrites(t *testing.T) {
+	rewrites := map[string]core.Rewrite{
+		"customer":       {"email": "faker.Internet.Email()"},
+		"order_customer": {"email": "faker.Internet.Email()"},
+	}
+
+	result := DeterministicRewrites(rewrites, "secret")
+
+	assert.Equal(t, result["customer"]["email"], result["order_customer"]["email"])
+	assert.Equal(t, "faker.Internet.Email()", rewrites["customer"]["email"], "input must not be modified")
+}
@@ -0,0 +1,145 @@
+package dbdump
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const (
+	TargetMariaDB = "mariadb"
+	TargetMySQL57 = "mysql57"
+	TargetMySQL8  = "mysql8"
+)
+
+var (
+	definerRegExp          = regexp.MustCompile("(?i)\\s*DEFINER\\s*=\\s*(`[^`]*`|'[^']*'|[^\\s@]+)@(`[^`]*`|'[^']*'|[^\\s*]+)")
+	mysql8CollationRegExp  = regexp.MustCompile(`\b(utf8mb4)_0900_(\w+)`)
+	mariadbCollationRegExp = regexp.MustCompile(`\b(utf8mb[34])_uca1400_(\w+)`)
+	generatedJsonRegExp    = regexp.MustCompile(`(?i)\bjson(\s+GENERATED\s+ALWAYS\s+AS)\b`)
+	persistentRegExp       = regexp.MustCompile(`(?i)(\bGENERATED\s+ALWAYS\s+AS\s*\(.*\))\s+PERSISTENT\b`)
+)
+
+// IsValidTarget reports whether the target is a supported server flavour. An empty target disables the rewriting.
+func IsValidTarget(target string) bool {
+	return target == "" || target == TargetMariaDB || target == TargetMySQL57 || target == TargetMySQL8
+}
+
+// RewriteForTarget rewrites a line of a dump, so it can be imported into the target server flavour. Data lines are returned unchanged.
+func RewriteForTarget(line, target string) string {
+	if target == "" || isDataLine(line) {
+		return line
+	}
+
+	line = definerRegExp.ReplaceAllString(line, "")
+
+	switch target {
+	case TargetMariaDB:
+		line = replaceCollation(mysql8CollationRegExp, line)
+		line = generatedJsonRegExp.ReplaceAllString(line, "longtext$1")
+	case TargetMySQL57:
+		line = replaceCollation(mysql8CollationRegExp, line)
+		line = replaceCollation(mariadbCollationRegExp, line)
+		line = persistentRegExp.ReplaceAllString(line, "$1 STORED")
+	case TargetMySQL8:
+		line = replaceCollation(mariadbCollationRegExp, line)
+		line = persistentRegExp.ReplaceAllString(line, "$1 STORED")
+	}
+
+	return line
+}
+
+// replaceCollation replaces a collation with the unicode_ci or bin collation of the same charset, which are known to all server flavours.
+func replaceCollation(re *regexp.Regexp, line string) string {
+	return re.ReplaceAllStringFunc(line, func(collation string) string {
+		match := re.FindStringSubmatch(collation)
+
+		if strings.Contains(match[2], "_cs") || strings.HasSuffix(match[2], "bin") {
+			return match[1] + "_bin"
+		}
+
+		return match[1] + "_unicode_ci"
+	})
+}
+
+// isDataLine reports whether the line belongs to an INSERT statement, go-mad writes every row into an own line.
+func isDataLine(line string) bool {
+	return strings.HasPrefix(line, "INSERT INTO ") || strings.HasPrefix(line, "( ")
+}
+
+type targetWriter struct {
+	w           io.Writer
+	target      string
+	line        bytes.Buffer
+	passthrough bool
+}
+
+// NewTargetWriter returns a writer rewriting the statements of a dump for the target server flavour. Close flushes the last line, but does not close w.
+func NewTargetWriter(w io.Writer, target string) (io.WriteCloser, error) {
+	if !IsValidTarget(target) {
+		return nil, fmt.Errorf("unsupported target %q, supported are %s, %s and %s", target, TargetMariaDB, TargetMySQL57, TargetMySQL8)
+	}
+
+	return &targetWriter{w: w, target: target}, nil
+}
+
+func (t *targetWriter) Write(p []byte) (int, error) {
+	written := len(p)
+
+	for len(p) > 0 {
+		end := bytes.IndexByte(p, '\n')
+		chunk := p
+
+		if end >= 0 {
+			chunk = p[:end+1]
+		}
+
+		p = p[len(chunk):]
+
+		if t.passthrough {
+			if _, err := t.w.Write(chunk); err != nil {
+				return 0, err
+			}
+		} else {
+			t.line.Write(chunk)
+
+			// data lines can be large, so they are not buffered once they are detected
+			if t.line.Len() >= len("INSERT INTO ") && isDataLine(t.line.String()) {
+				t.passthrough = true
+
+				if _, err := t.w.Write(t.line.Bytes()); err != nil {
+					return 0, err
+				}
+
+				t.line.Reset()
+			}
+		}
+
+		if end >= 0 {
+			if err := t.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+func (t *targetWriter) flush() error {
+	t.passthrough = false
+
+	if t.line.Len() == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(t.w, RewriteForTarget(t.line.String(), t.target))
+	t.line.Reset()
+
+	return err
+}
+
+func (t *targetWriter) Close() error {
+	return t.flush()
+}
@@ -0,0 +1,108 @@
+package dbdump
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteForTarget(t *testing.T) {
+	cases := []struct {
+		name     string
+		target   string
+		line     string
+		expected string
+	}{
+		{
+			name:     "mysql 8 collation for mariadb",
+			target:   TargetMariaDB,
+			line:     ") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;\n",
+			expected: ") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;\n",
+		},
+		{
+			name:     "case sensitive collation",
+			target:   TargetMySQL57,
+			line:     "  `name` varchar(255) COLLATE utf8mb4_0900_as_cs NOT NULL,\n",
+			expected: "  `name` varchar(255) COLLATE utf8mb4_bin NOT NULL,\n",
+		},
+		{
+			name:     "mysql 8 collation is kept for mysql 8",
+			target:   TargetMySQL8,
+			line:     ") DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;\n",
+			expected: ") DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;\n",
+		},
+		{
+			name:     "mariadb collation for mysql 8",
+			target:   TargetMySQL8,
+			line:     ") DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_uca1400_ai_ci;\n",
+			expected: ") DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;\n",
+		},
+		{
+			name:     "generated json column for mariadb",
+			target:   TargetMariaDB,
+			line:     "  `states` json GENERATED ALWAYS AS (json_extract(`custom_fields`,_utf8mb4'$.states')) VIRTUAL,\n",
+			expected: "  `states` longtext GENERATED ALWAYS AS (json_extract(`custom_fields`,_utf8mb4'$.states')) VIRTUAL,\n",
+		},
+		{
+			name:     "persistent generated column for mysql",
+			target:   TargetMySQL57,
+			line:     "  `name` varchar(255) GENERATED ALWAYS AS (json_unquote(json_extract(`data`,'$.name'))) PERSISTENT,\n",
+			expected: "  `name` varchar(255) GENERATED ALWAYS AS (json_unquote(json_extract(`data`,'$.name'))) STORED,\n",
+		},
+		{
+			name:     "definer of trigger",
+			target:   TargetMySQL8,
+			line:     "CREATE DEFINER=`root`@`%` TRIGGER order_insert BEFORE INSERT ON `order` FOR EACH ROW BEGIN\n",
+			expected: "CREATE TRIGGER order_insert BEFORE INSERT ON `order` FOR EACH ROW BEGIN\n",
+		},
+		{
+			name:     "definer in versioned comment",
+			target:   TargetMariaDB,
+			line:     "/*!50013 DEFINER=root@localhost SQL SECURITY DEFINER */\n",
+			expected: "/*!50013 SQL SECURITY DEFINER */\n",
+		},
+		{
+			name:     "data is not touched",
+			target:   TargetMariaDB,
+			line:     "( 'utf8mb4_0900_ai_ci', 'DEFINER=`root`@`%`' ),\n",
+			expected: "( 'utf8mb4_0900_ai_ci', 'DEFINER=`root`@`%`' ),\n",
+		},
+		{
+			name:     "without target",
+			target:   "",
+			line:     "COLLATE=utf8mb4_0900_ai_ci;\n",
+			expected: "COLLATE=utf8mb4_0900_ai_ci;\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, RewriteForTarget(tc.line, tc.target))
+		})
+	}
+}
+
+func TestTargetWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := NewTargetWriter(&buf, TargetMariaDB)
+	assert.NoError(t, err)
+
+	dump := "CREATE TABLE `a` (\n  `id` int\n) COLLATE=utf8mb4_0900_ai_ci;\nINSERT INTO `a` (`id`) VALUES\n( 'utf8mb4_0900_ai_ci' );\nSET NAMES utf8mb4 COLLATE utf8mb4_0900_ai_ci;"
+	expected := "CREATE TABLE `a` (\n  `id` int\n) COLLATE=utf8mb4_unicode_ci;\nINSERT INTO `a` (`id`) VALUES\n( 'utf8mb4_0900_ai_ci' );\nSET NAMES utf8mb4 COLLATE utf8mb4_unicode_ci;"
+
+	// write in small chunks, so lines are split across writes
+	for _, chunk := range strings.SplitAfter(dump, "0") {
+		n, err := w.Write([]byte(chunk))
+		assert.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+
+	assert.NoError(t, w.Close())
+	assert.Equal(t, expected, buf.String())
+
+	_, err = NewTargetWriter(&buf, "postgres")
+	assert.Error(t, err)
+}
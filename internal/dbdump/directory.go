@@ -0,0 +1,371 @@
+package dbdump
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/doutorfinancas/go-mad/core"
+	"github.com/doutorfinancas/go-mad/database"
+	"github.com/doutorfinancas/go-mad/generator"
+	"github.com/go-sql-driver/mysql"
+	"github.com/gobwas/glob"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+)
+
+const triggersFileName = "triggers.sql"
+
+// DumperOptions returns the go-mad options used for all dumps.
+func DumperOptions(skipLockTables, dumpTriggers bool) []database.Option {
+	opt := []database.Option{
+		database.OptionValue("hex-encode", "1"),
+		database.OptionValue("set-charset", "utf8mb4"),
+		database.OptionValue("skip-definer", ""),
+	}
+
+	if dumpTriggers {
+		opt = append(opt, database.OptionValue("dump-trigger", ""), database.OptionValue("trigger-delimiter", "//"))
+	}
+
+	if skipLockTables {
+		opt = append(opt, database.OptionValue("skip-lock-tables", "1"))
+	}
+
+	return opt
+}
+
+type DirectoryDumpOptions struct {
+	Rules          core.Rules
+	Compression    string
+	Workers        int
+	SkipLockTables bool
+	Target         string
+	// Recipients the files are encrypted for, the manifest is not encrypted
+	Recipients []age.Recipient
+}
+
+type directoryTable struct {
+	name string
+	size int64
+}
+
+// DumpDirectory dumps every table into an own file of the directory. The tables are dumped concurrently, every worker uses an own connection inside a transaction with a consistent snapshot.
+func DumpDirectory(ctx context.Context, cfg *mysql.Config, dir string, options DirectoryDumpOptions) (*Manifest, error) {
+	if options.Workers < 1 {
+		options.Workers = 1
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	coordinator, err := openSingleConnection(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer coordinator.Close()
+
+	tables, err := listDirectoryTables(ctx, coordinator, options.Rules.Ignore)
+	if err != nil {
+		return nil, err
+	}
+
+	if !options.SkipLockTables {
+		if _, err := coordinator.ExecContext(ctx, "FLUSH TABLES WITH READ LOCK"); err != nil {
+			return nil, fmt.Errorf("%w, you maybe want to disable locking with --skip-lock-tables", err)
+		}
+	}
+
+	workers := make([]*sql.DB, 0, options.Workers)
+
+	defer func() {
+		for _, worker := range workers {
+			_ = worker.Close()
+		}
+	}()
+
+	for i := 0; i < options.Workers; i++ {
+		worker, err := openSnapshotConnection(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		workers = append(workers, worker)
+	}
+
+	if !options.SkipLockTables {
+		if _, err := coordinator.ExecContext(ctx, "UNLOCK TABLES"); err != nil {
+			return nil, err
+		}
+	}
+
+	manifest := &Manifest{
+		Version:     manifestVersion,
+		CreatedAt:   time.Now().UTC(),
+		Compression: options.Compression,
+		Encrypted:   len(options.Recipients) > 0,
+		Tables:      make([]ManifestTable, len(tables)),
+	}
+
+	allTables := make([]string, 0, len(tables))
+	for _, table := range tables {
+		allTables = append(allTables, table.name)
+	}
+
+	jobs := make(chan int)
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	for _, worker := range workers {
+		group.Go(func() error {
+			for index := range jobs {
+				table := tables[index].name
+
+				logging.FromContext(ctx).Infof("Dumping table %s", table)
+
+				entry, err := dumpDirectoryTable(ctx, worker, dir, table, allTables, options)
+				if err != nil {
+					return fmt.Errorf("dumping table %s: %w", table, err)
+				}
+
+				manifest.Tables[index] = *entry
+			}
+
+			return nil
+		})
+	}
+
+	group.Go(func() error {
+		defer close(jobs)
+
+		for _, index := range tablesBySize(tables) {
+			select {
+			case jobs <- index:
+			case <-groupCtx.Done():
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	triggers, err := dumpDirectoryFile(dir, triggersFileName, options, func(w io.Writer) error {
+		return dumpWithFilter(ctx, workers[0], w, options.Rules, options.Target, DumperOptions(true, true), nil, allTables)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifest.Triggers = triggers
+
+	if err := manifest.Write(dir); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func dumpDirectoryTable(ctx context.Context, db *sql.DB, dir, table string, allTables []string, options DirectoryDumpOptions) (*ManifestTable, error) {
+	others := make([]string, 0, len(allTables)-1)
+
+	for _, other := range allTables {
+		if other != table {
+			others = append(others, other)
+		}
+	}
+
+	file, err := dumpDirectoryFile(dir, table+".sql", options, func(w io.Writer) error {
+		return dumpWithFilter(ctx, db, w, options.Rules, options.Target, DumperOptions(true, false), options.Rules.NoData, others)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &ManifestTable{ManifestFile: *file, Name: table}
+
+	if matchesAny(table, options.Rules.NoData) {
+		return entry, nil
+	}
+
+	query := fmt.Sprintf("SELECT COUNT(*) FROM `%s`", table)
+
+	if where, ok := options.Rules.Where[table]; ok {
+		query = fmt.Sprintf("%s WHERE %s", query, where)
+	}
+
+	if err := db.QueryRowContext(ctx, query).Scan(&entry.Rows); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func dumpDirectoryFile(dir, name string, options DirectoryDumpOptions, dump func(w io.Writer) error) (*ManifestFile, error) {
+	name += FileExtension(options.Compression)
+
+	if len(options.Recipients) > 0 {
+		name += EncryptedFileExtension
+	}
+
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	hasher := sha256.New()
+
+	encrypted, err := NewEncryptingWriter(io.MultiWriter(file, hasher), options.Recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := NewWriter(encrypted, options.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dump(w); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := encrypted.Close(); err != nil {
+		return nil, err
+	}
+
+	return &ManifestFile{File: name, Checksum: hex.EncodeToString(hasher.Sum(nil))}, file.Close()
+}
+
+func dumpWithFilter(ctx context.Context, db *sql.DB, w io.Writer, rules core.Rules, target string, opt []database.Option, noData, ignore []string) error {
+	dumper, err := database.NewMySQLDumper(db, logging.FromContext(ctx).Desugar(), generator.NewService(), opt...)
+	if err != nil {
+		return err
+	}
+
+	dumper.SetSelectMap(rules.RewriteToMap())
+	dumper.SetWhereMap(rules.Where)
+
+	if err := dumper.SetFilterMap(noData, ignore); err != nil {
+		return err
+	}
+
+	targetWriter, err := NewTargetWriter(w, target)
+	if err != nil {
+		return err
+	}
+
+	if err := dumper.Dump(targetWriter); err != nil {
+		return err
+	}
+
+	return targetWriter.Close()
+}
+
+// openSingleConnection opens a pool limited to one connection, so all statements share the same session.
+func openSingleConnection(cfg *mysql.Config) (*sql.DB, error) {
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	return db, nil
+}
+
+func openSnapshotConnection(ctx context.Context, cfg *mysql.Config) (*sql.DB, error) {
+	db, err := openSingleConnection(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, statement := range []string{"SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ", "START TRANSACTION WITH CONSISTENT SNAPSHOT"} {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			_ = db.Close()
+
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+func listDirectoryTables(ctx context.Context, db *sql.DB, ignore []string) ([]directoryTable, error) {
+	rows, err := db.QueryContext(ctx, "SELECT TABLE_NAME, COALESCE(DATA_LENGTH, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tables := make([]directoryTable, 0)
+
+	for rows.Next() {
+		var table directoryTable
+
+		if err := rows.Scan(&table.name, &table.size); err != nil {
+			return nil, err
+		}
+
+		if matchesAny(table.name, ignore) {
+			continue
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
+
+// tablesBySize returns the indexes of the tables with the biggest tables first, so the workers are used evenly.
+func tablesBySize(tables []directoryTable) []int {
+	indexes := make([]int, len(tables))
+
+	for i := range tables {
+		indexes[i] = i
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return tables[indexes[i]].size > tables[indexes[j]].size
+	})
+
+	return indexes
+}
+
+func matchesAny(table string, patterns []string) bool {
+	for _, pattern := range patterns {
+		g, err := glob.Compile(pattern)
+		if err != nil {
+			continue
+		}
+
+		if g.Match(table) || strings.EqualFold(pattern, table) {
+			return true
+		}
+	}
+
+	return false
+}
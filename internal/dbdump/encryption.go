@@ -0,0 +1,129 @@
+package dbdump
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+const EncryptedFileExtension = ".age"
+
+var (
+	ageMagic = []byte("age-encryption.org/")
+
+	ErrDumpEncrypted = errors.New("the dump is encrypted, pass an identity or the passphrase to decrypt it")
+)
+
+// ParseRecipients parses age X25519 recipients (age1...) or files containing them. A passphrase cannot be combined with recipients.
+func ParseRecipients(recipients []string, passphrase string) ([]age.Recipient, error) {
+	if passphrase != "" {
+		if len(recipients) > 0 {
+			return nil, fmt.Errorf("a passphrase cannot be combined with recipients")
+		}
+
+		recipient, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return nil, err
+		}
+
+		return []age.Recipient{recipient}, nil
+	}
+
+	parsed := make([]age.Recipient, 0, len(recipients))
+
+	for _, recipient := range recipients {
+		if strings.HasPrefix(recipient, "age1") {
+			r, err := age.ParseX25519Recipient(recipient)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recipient %s: %w", recipient, err)
+			}
+
+			parsed = append(parsed, r)
+
+			continue
+		}
+
+		content, err := os.ReadFile(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("could not read recipients file: %w", err)
+		}
+
+		fileRecipients, err := age.ParseRecipients(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse recipients file %s: %w", recipient, err)
+		}
+
+		parsed = append(parsed, fileRecipients...)
+	}
+
+	return parsed, nil
+}
+
+// ParseIdentities parses age identity files and the passphrase used to decrypt a dump.
+func ParseIdentities(identityFiles []string, passphrase string) ([]age.Identity, error) {
+	identities := make([]age.Identity, 0, len(identityFiles)+1)
+
+	for _, identityFile := range identityFiles {
+		content, err := os.ReadFile(identityFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read identity file: %w", err)
+		}
+
+		fileIdentities, err := age.ParseIdentities(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse identity file %s: %w", identityFile, err)
+		}
+
+		identities = append(identities, fileIdentities...)
+	}
+
+	if passphrase != "" {
+		identity, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+
+		identities = append(identities, identity)
+	}
+
+	return identities, nil
+}
+
+// NewEncryptingWriter returns a writer encrypting the stream for the recipients. Without recipients the stream is written unchanged.
+func NewEncryptingWriter(w io.Writer, recipients []age.Recipient) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nopWriteCloser{w}, nil
+	}
+
+	return age.Encrypt(w, recipients...)
+}
+
+// IsEncrypted reports whether the stream is encrypted with age.
+func IsEncrypted(r *bufio.Reader) bool {
+	header, err := r.Peek(len(ageMagic))
+
+	return err == nil && bytes.Equal(header, ageMagic)
+}
+
+func decrypt(r *bufio.Reader, identities []age.Identity) (*bufio.Reader, error) {
+	if !IsEncrypted(r) {
+		return r, nil
+	}
+
+	if len(identities) == 0 {
+		return nil, ErrDumpEncrypted
+	}
+
+	decrypted, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt the dump: %w", err)
+	}
+
+	return bufio.NewReader(decrypted), nil
+}
@@ -0,0 +1,103 @@
+package dbdump
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+)
+
+func encryptDump(t *testing.T, recipients []age.Recipient, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	encrypted, err := NewEncryptingWriter(&buf, recipients)
+	assert.NoError(t, err)
+
+	w, err := NewWriter(encrypted, CompressionZstd)
+	assert.NoError(t, err)
+
+	_, err = w.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.NoError(t, encrypted.Close())
+
+	return buf.Bytes()
+}
+
+func readDump(t *testing.T, data []byte, identities []age.Identity) (string, error) {
+	t.Helper()
+
+	reader, err := NewReader(bytes.NewReader(data), identities...)
+	if err != nil {
+		return "", err
+	}
+
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+
+	return string(content), err
+}
+
+func TestEncryptionWithRecipient(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	identityFile := filepath.Join(dir, "key.txt")
+	assert.NoError(t, os.WriteFile(identityFile, []byte(identity.String()+"\n"), 0o600))
+
+	recipients, err := ParseRecipients([]string{identity.Recipient().String()}, "")
+	assert.NoError(t, err)
+
+	data := encryptDump(t, recipients, "SELECT 1;")
+	assert.True(t, bytes.HasPrefix(data, ageMagic))
+
+	identities, err := ParseIdentities([]string{identityFile}, "")
+	assert.NoError(t, err)
+
+	content, err := readDump(t, data, identities)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 1;", content)
+
+	_, err = readDump(t, data, nil)
+	assert.ErrorIs(t, err, ErrDumpEncrypted)
+}
+
+func TestEncryptionWithPassphrase(t *testing.T) {
+	recipients, err := ParseRecipients(nil, "secret")
+	assert.NoError(t, err)
+
+	data := encryptDump(t, recipients, "SELECT 1;")
+
+	identities, err := ParseIdentities(nil, "secret")
+	assert.NoError(t, err)
+
+	content, err := readDump(t, data, identities)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 1;", content)
+
+	wrong, err := ParseIdentities(nil, "wrong")
+	assert.NoError(t, err)
+
+	_, err = readDump(t, data, wrong)
+	assert.ErrorContains(t, err, "could not decrypt the dump")
+}
+
+func TestParseRecipients(t *testing.T) {
+	_, err := ParseRecipients([]string{"age1invalid"}, "")
+	assert.ErrorContains(t, err, "invalid recipient")
+
+	_, err = ParseRecipients([]string{"age1invalid"}, "secret")
+	assert.ErrorContains(t, err, "cannot be combined")
+
+	recipients, err := ParseRecipients(nil, "")
+	assert.NoError(t, err)
+	assert.Empty(t, recipients)
+}
@@ -0,0 +1,142 @@
+package dbdump
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"filippo.io/age"
+	"golang.org/x/sync/errgroup"
+)
+
+const maxStatementErrorLength = 200
+
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Import executes all statements of the dump on the given connection and returns the amount of executed statements.
+func Import(ctx context.Context, db Execer, r io.Reader) (int, error) {
+	scanner := NewScanner(r)
+	count := 0
+
+	for scanner.Next() {
+		statement := scanner.Statement()
+
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return count, fmt.Errorf("statement %d failed: %w: %s", count+1, err, truncateStatement(statement))
+		}
+
+		count++
+	}
+
+	return count, scanner.Err()
+}
+
+func truncateStatement(statement string) string {
+	if len(statement) <= maxStatementErrorLength {
+		return statement
+	}
+
+	return statement[:maxStatementErrorLength] + "..."
+}
+
+// ImportDirectory imports a dump in the directory format. The tables are imported concurrently, the triggers at the end.
+func ImportDirectory(ctx context.Context, db *sql.DB, dir string, workers int, identities []age.Identity, onFileDone func(file string)) error {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := manifest.Verify(dir); err != nil {
+		return err
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan string)
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	for i := 0; i < workers; i++ {
+		group.Go(func() error {
+			conn, err := db.Conn(groupCtx)
+			if err != nil {
+				return err
+			}
+
+			defer conn.Close()
+
+			for file := range jobs {
+				if err := importFile(groupCtx, conn, filepath.Join(dir, file), identities); err != nil {
+					return fmt.Errorf("importing %s: %w", file, err)
+				}
+
+				onFileDone(file)
+			}
+
+			return nil
+		})
+	}
+
+	group.Go(func() error {
+		defer close(jobs)
+
+		for _, table := range manifest.Tables {
+			select {
+			case jobs <- table.File:
+			case <-groupCtx.Done():
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	if err := group.Wait(); err != nil {
+		return err
+	}
+
+	if manifest.Triggers == nil {
+		return nil
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if err := importFile(ctx, conn, filepath.Join(dir, manifest.Triggers.File), identities); err != nil {
+		return fmt.Errorf("importing %s: %w", manifest.Triggers.File, err)
+	}
+
+	onFileDone(manifest.Triggers.File)
+
+	return nil
+}
+
+func importFile(ctx context.Context, db Execer, path string, identities []age.Identity) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	reader, err := NewReader(file, identities...)
+	if err != nil {
+		return err
+	}
+
+	defer reader.Close()
+
+	_, err = Import(ctx, db, reader)
+
+	return err
+}
@@ -0,0 +1,114 @@
+package dbdump
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	ManifestFileName = "manifest.json"
+	manifestVersion  = 1
+)
+
+// Manifest describes a dump in the directory format.
+type Manifest struct {
+	Version     int             `json:"version"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	Compression string          `json:"compression"`
+	Encrypted   bool            `json:"encrypted,omitempty"`
+	Tables      []ManifestTable `json:"tables"`
+	Triggers    *ManifestFile   `json:"triggers,omitempty"`
+}
+
+type ManifestFile struct {
+	File     string `json:"file"`
+	Checksum string `json:"checksum"`
+}
+
+type ManifestTable struct {
+	ManifestFile
+	Name string `json:"name"`
+	Rows uint64 `json:"rows"`
+}
+
+func ReadManifest(dir string) (*Manifest, error) {
+	content, err := os.ReadFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read dump manifest: %w", err)
+	}
+
+	var manifest Manifest
+
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("cannot parse dump manifest: %w", err)
+	}
+
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported dump manifest version %d", manifest.Version)
+	}
+
+	return &manifest, nil
+}
+
+func (m Manifest) Write(dir string) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, ManifestFileName), content, 0o644)
+}
+
+// Files returns all files of the dump, the triggers are always the last entry.
+func (m Manifest) Files() []ManifestFile {
+	files := make([]ManifestFile, 0, len(m.Tables)+1)
+
+	for _, table := range m.Tables {
+		files = append(files, table.ManifestFile)
+	}
+
+	if m.Triggers != nil {
+		files = append(files, *m.Triggers)
+	}
+
+	return files
+}
+
+// Verify compares the checksums of all files with the manifest.
+func (m Manifest) Verify(dir string) error {
+	for _, file := range m.Files() {
+		checksum, err := fileChecksum(filepath.Join(dir, file.File))
+		if err != nil {
+			return err
+		}
+
+		if checksum != file.Checksum {
+			return fmt.Errorf("checksum mismatch of %s, the dump is corrupted", file.File)
+		}
+	}
+
+	return nil
+}
+
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	hasher := sha256.New()
+
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
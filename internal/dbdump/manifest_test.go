@@ -0,0 +1,45 @@
+package dbdump
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestVerify(t *testing.T) {
+	dir := t.TempDir()
+
+	file, err := dumpDirectoryFile(dir, "product.sql", DirectoryDumpOptions{Compression: CompressionGzip}, func(w io.Writer) error {
+		_, err := w.Write([]byte("INSERT INTO `product` VALUES (1);"))
+
+		return err
+	})
+	assert.NoError(t, err)
+
+	manifest := Manifest{Version: manifestVersion, Compression: CompressionGzip, Tables: []ManifestTable{{ManifestFile: *file, Name: "product", Rows: 1}}}
+	assert.NoError(t, manifest.Write(dir))
+
+	read, err := ReadManifest(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "product", read.Tables[0].Name)
+	assert.Equal(t, "product.sql.gz", read.Tables[0].File)
+	assert.NoError(t, read.Verify(dir))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "product.sql.gz"), []byte("corrupted"), 0o644))
+	assert.ErrorContains(t, read.Verify(dir), "checksum mismatch of product.sql.gz")
+}
+
+func TestTablesBySize(t *testing.T) {
+	tables := []directoryTable{{name: "a", size: 10}, {name: "b", size: 300}, {name: "c", size: 20}}
+
+	assert.Equal(t, []int{1, 2, 0}, tablesBySize(tables))
+}
+
+func TestMatchesAny(t *testing.T) {
+	assert.True(t, matchesAny("log_entry", []string{"log_*"}))
+	assert.True(t, matchesAny("cart", []string{"product", "cart"}))
+	assert.False(t, matchesAny("cart", []string{"product"}))
+}
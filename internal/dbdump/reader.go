@@ -0,0 +1,79 @@
+package dbdump
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync/atomic"
+
+	"filippo.io/age"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// DetectCompression returns the compression of the stream by looking at the magic bytes.
+func DetectCompression(r *bufio.Reader) string {
+	if header, err := r.Peek(len(zstdMagic)); err == nil && bytes.Equal(header, zstdMagic) {
+		return CompressionZstd
+	}
+
+	if header, err := r.Peek(len(gzipMagic)); err == nil && bytes.Equal(header, gzipMagic) {
+		return CompressionGzip
+	}
+
+	return CompressionNone
+}
+
+// NewReader returns a reader which transparently decrypts and decompresses the given stream.
+func NewReader(r io.Reader, identities ...age.Identity) (io.ReadCloser, error) {
+	buffered, err := decrypt(bufio.NewReader(r), identities)
+	if err != nil {
+		return nil, err
+	}
+
+	switch DetectCompression(buffered) {
+	case CompressionGzip:
+		return gzip.NewReader(buffered)
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.IOReadCloser(), nil
+	}
+
+	return io.NopCloser(buffered), nil
+}
+
+// CountingReader counts the bytes read from the underlying reader.
+type CountingReader struct {
+	io.Reader
+	count atomic.Int64
+}
+
+func NewCountingReader(r io.Reader) *CountingReader {
+	return &CountingReader{Reader: r}
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.count.Add(int64(n))
+
+	return n, err
+}
+
+func (c *CountingReader) Count() int64 {
+	return c.count.Load()
+}
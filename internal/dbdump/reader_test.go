@@ -0,0 +1,38 @@
+package dbdump
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReaderDetectsCompression(t *testing.T) {
+	content := []byte("SELECT 1;")
+
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write(content)
+	assert.NoError(t, gzipWriter.Close())
+
+	var zstded bytes.Buffer
+	zstdWriter, err := zstd.NewWriter(&zstded)
+	assert.NoError(t, err)
+	_, _ = zstdWriter.Write(content)
+	assert.NoError(t, zstdWriter.Close())
+
+	for name, input := range map[string][]byte{"plain": content, "gzip": gzipped.Bytes(), "zstd": zstded.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			reader, err := NewReader(bytes.NewReader(input))
+			assert.NoError(t, err)
+
+			decoded, err := io.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.Equal(t, content, decoded)
+			assert.NoError(t, reader.Close())
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package dbdump
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+)
+
+const defaultDelimiter = ";"
+
+const (
+	stateNormal = iota
+	stateSingleQuote
+	stateDoubleQuote
+	stateBacktick
+	stateLineComment
+	stateBlockComment
+	stateConditionalComment
+)
+
+// Scanner splits a SQL dump into single statements. It understands quoting, comments and the DELIMITER command of the mysql client.
+type Scanner struct {
+	r         *bufio.Reader
+	delimiter string
+	statement string
+	buf       bytes.Buffer
+	err       error
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		r:         bufio.NewReaderSize(r, 1024*1024),
+		delimiter: defaultDelimiter,
+	}
+}
+
+// Next reads the next statement, returns false when the input is exhausted or an error occurred.
+func (s *Scanner) Next() bool {
+	if s.err != nil {
+		return false
+	}
+
+	s.buf.Reset()
+
+	state := stateNormal
+	lineStart := true
+
+	for {
+		if state == stateNormal && lineStart && isBlank(s.buf.Bytes()) {
+			handled, err := s.readDelimiterCommand()
+			if err != nil {
+				return s.finish(err)
+			}
+
+			if handled {
+				continue
+			}
+		}
+
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return s.finish(err)
+		}
+
+		lineStart = c == '\n'
+
+		switch state {
+		case stateNormal:
+			if c == s.delimiter[0] && s.hasDelimiterRest() {
+				if _, err := s.r.Discard(len(s.delimiter) - 1); err != nil {
+					return s.finish(err)
+				}
+
+				if s.emit() {
+					return true
+				}
+
+				continue
+			}
+
+			switch c {
+			case '\'':
+				state = stateSingleQuote
+			case '"':
+				state = stateDoubleQuote
+			case '`':
+				state = stateBacktick
+			case '#':
+				state = stateLineComment
+				continue
+			case '-':
+				if s.peekIs("-") && s.isLineCommentStart() {
+					state = stateLineComment
+					continue
+				}
+			case '/':
+				if s.peekIs("*!") {
+					state = stateConditionalComment
+				} else if s.peekIs("*") {
+					if _, err := s.r.Discard(1); err != nil {
+						return s.finish(err)
+					}
+
+					state = stateBlockComment
+					continue
+				}
+			}
+
+			s.buf.WriteByte(c)
+		case stateSingleQuote, stateDoubleQuote:
+			s.buf.WriteByte(c)
+
+			if c == '\\' {
+				next, err := s.r.ReadByte()
+				if err != nil {
+					return s.finish(err)
+				}
+
+				s.buf.WriteByte(next)
+				continue
+			}
+
+			if (state == stateSingleQuote && c == '\'') || (state == stateDoubleQuote && c == '"') {
+				state = stateNormal
+			}
+		case stateBacktick:
+			s.buf.WriteByte(c)
+
+			if c == '`' {
+				state = stateNormal
+			}
+		case stateLineComment:
+			if c == '\n' {
+				state = stateNormal
+				s.buf.WriteByte(c)
+			}
+		case stateBlockComment:
+			if c == '*' && s.peekIs("/") {
+				if _, err := s.r.Discard(1); err != nil {
+					return s.finish(err)
+				}
+
+				state = stateNormal
+				s.buf.WriteByte(' ')
+			}
+		case stateConditionalComment:
+			s.buf.WriteByte(c)
+
+			if c == '*' && s.peekIs("/") {
+				if _, err := s.r.Discard(1); err != nil {
+					return s.finish(err)
+				}
+
+				s.buf.WriteByte('/')
+				state = stateNormal
+			}
+		}
+	}
+}
+
+// Statement returns the last statement read by Next.
+func (s *Scanner) Statement() string {
+	return s.statement
+}
+
+// Err returns the first error that occurred, io.EOF is not reported.
+func (s *Scanner) Err() error {
+	if errors.Is(s.err, io.EOF) {
+		return nil
+	}
+
+	return s.err
+}
+
+func (s *Scanner) finish(err error) bool {
+	if errors.Is(err, io.EOF) {
+		s.err = io.EOF
+
+		return s.emit()
+	}
+
+	s.err = err
+
+	return false
+}
+
+func (s *Scanner) emit() bool {
+	statement := strings.TrimSpace(s.buf.String())
+	statement = strings.TrimSpace(strings.TrimRight(statement, ";"))
+
+	s.buf.Reset()
+
+	if statement == "" {
+		return false
+	}
+
+	s.statement = statement
+
+	return true
+}
+
+func (s *Scanner) hasDelimiterRest() bool {
+	if len(s.delimiter) == 1 {
+		return true
+	}
+
+	return s.peekIs(s.delimiter[1:])
+}
+
+func (s *Scanner) peekIs(expected string) bool {
+	peek, err := s.r.Peek(len(expected))
+	if err != nil {
+		return false
+	}
+
+	return string(peek) == expected
+}
+
+func (s *Scanner) isLineCommentStart() bool {
+	peek, err := s.r.Peek(2)
+	if err != nil {
+		// "--" at the end of the input
+		return len(peek) == 1
+	}
+
+	return peek[1] == ' ' || peek[1] == '\t' || peek[1] == '\n' || peek[1] == '\r'
+}
+
+func (s *Scanner) readDelimiterCommand() (bool, error) {
+	const command = "DELIMITER "
+
+	peek, err := s.r.Peek(len(command))
+	if err != nil || !strings.EqualFold(string(peek), command) {
+		return false, nil
+	}
+
+	line, err := s.r.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	delimiter := strings.TrimSpace(line[len(command):])
+
+	if delimiter != "" {
+		s.delimiter = delimiter
+	}
+
+	s.buf.Reset()
+
+	return true, nil
+}
+
+func isBlank(b []byte) bool {
+	return len(bytes.TrimSpace(b)) == 0
+}
@@ -0,0 +1,53 @@
+package dbdump
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scanAll(t *testing.T, input string) []string {
+	t.Helper()
+
+	scanner := NewScanner(strings.NewReader(input))
+	statements := make([]string, 0)
+
+	for scanner.Next() {
+		statements = append(statements, scanner.Statement())
+	}
+
+	assert.NoError(t, scanner.Err())
+
+	return statements
+}
+
+func TestScannerSimpleStatements(t *testing.T) {
+	statements := scanAll(t, "SET NAMES utf8mb4;\nSET FOREIGN_KEY_CHECKS = 0;\n\nSELECT 1")
+
+	assert.Equal(t, []string{"SET NAMES utf8mb4", "SET FOREIGN_KEY_CHECKS = 0", "SELECT 1"}, statements)
+}
+
+func TestScannerQuotes(t *testing.T) {
+	statements := scanAll(t, "INSERT INTO `a;b` VALUES ('it\\'s; fine', \"double;\", '-- no comment');\nSELECT 2;")
+
+	assert.Equal(t, []string{"INSERT INTO `a;b` VALUES ('it\\'s; fine', \"double;\", '-- no comment')", "SELECT 2"}, statements)
+}
+
+func TestScannerComments(t *testing.T) {
+	statements := scanAll(t, "--\n-- Data for table `foo` -- 2 rows\n--\n\n# hash comment\nSELECT /* inline; */ 1;\n/*!40101 SET NAMES utf8mb4 */;\nSELECT 5-1;")
+
+	assert.Equal(t, []string{"SELECT   1", "/*!40101 SET NAMES utf8mb4 */", "SELECT 5-1"}, statements)
+}
+
+func TestScannerTriggerDelimiter(t *testing.T) {
+	input := "SET FOREIGN_KEY_CHECKS = 1;\n\n--\n-- Trigger `foo`\n--\n\nDELIMITER //\nCREATE TRIGGER foo BEFORE INSERT ON bar FOR EACH ROW BEGIN\n  SET NEW.a = 1;\n  SET NEW.b = 2;\nEND;\n//\nDELIMITER ;\nSELECT 1;\n"
+
+	statements := scanAll(t, input)
+
+	assert.Equal(t, []string{
+		"SET FOREIGN_KEY_CHECKS = 1",
+		"CREATE TRIGGER foo BEFORE INSERT ON bar FOR EACH ROW BEGIN\n  SET NEW.a = 1;\n  SET NEW.b = 2;\nEND",
+		"SELECT 1",
+	}, statements)
+}
@@ -0,0 +1,349 @@
+package dbdump
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ForeignKey describes a foreign key from Table.Columns to ReferencedTable.ReferencedColumns.
+type ForeignKey struct {
+	Name              string
+	Table             string
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+	// Unique is true, when the columns of the foreign key are covered by an unique index of the table
+	Unique bool
+	// Cascade is true, when the referencing rows are deleted together with the referenced row
+	Cascade bool
+}
+
+// LoadForeignKeys reads all foreign keys of the current database from the information_schema.
+func LoadForeignKeys(ctx context.Context, db *sql.DB) ([]ForeignKey, error) {
+	rows, err := db.QueryContext(ctx, `SELECT k.CONSTRAINT_NAME, k.TABLE_NAME, k.COLUMN_NAME, k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME, r.DELETE_RULE = 'CASCADE'
+		FROM information_schema.KEY_COLUMN_USAGE k
+		INNER JOIN information_schema.REFERENTIAL_CONSTRAINTS r ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.TABLE_NAME = k.TABLE_NAME AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME
+		WHERE k.TABLE_SCHEMA = DATABASE() AND k.REFERENCED_TABLE_SCHEMA = DATABASE()
+		ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	keys := make([]ForeignKey, 0)
+
+	for rows.Next() {
+		var name, table, column, referencedTable, referencedColumn string
+		var cascade bool
+
+		if err := rows.Scan(&name, &table, &column, &referencedTable, &referencedColumn, &cascade); err != nil {
+			return nil, err
+		}
+
+		if len(keys) > 0 && keys[len(keys)-1].Table == table && keys[len(keys)-1].Name == name {
+			key := &keys[len(keys)-1]
+			key.Columns = append(key.Columns, column)
+			key.ReferencedColumns = append(key.ReferencedColumns, referencedColumn)
+
+			continue
+		}
+
+		keys = append(keys, ForeignKey{
+			Name:              name,
+			Table:             table,
+			Columns:           []string{column},
+			ReferencedTable:   referencedTable,
+			ReferencedColumns: []string{referencedColumn},
+			Cascade:           cascade,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	uniqueIndexes, err := loadUniqueIndexes(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		for _, index := range uniqueIndexes[key.Table] {
+			if sameColumns(index, key.Columns) {
+				keys[i].Unique = true
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+func loadUniqueIndexes(ctx context.Context, db *sql.DB) (map[string][][]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT TABLE_NAME, INDEX_NAME, COLUMN_NAME FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND NON_UNIQUE = 0
+		ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	indexes := make(map[string][][]string)
+	lastIndex := ""
+
+	for rows.Next() {
+		var table, index, column string
+
+		if err := rows.Scan(&table, &index, &column); err != nil {
+			return nil, err
+		}
+
+		tableIndexes := indexes[table]
+
+		if lastIndex == table+"."+index {
+			tableIndexes[len(tableIndexes)-1] = append(tableIndexes[len(tableIndexes)-1], column)
+		} else {
+			tableIndexes = append(tableIndexes, []string{column})
+		}
+
+		indexes[table] = tableIndexes
+		lastIndex = table + "." + index
+	}
+
+	return indexes, rows.Err()
+}
+
+// SubsetValues returns the distinct values of the columns of the rows matching the condition, formatted as SQL literals.
+// A value of multiple columns is a tuple like (X'01', 2), rows with a NULL value are skipped.
+type SubsetValues func(ctx context.Context, table string, columns []string, where string) ([]string, error)
+
+// SubsetWhere extends the where conditions of the root tables to all tables depending on them.
+//
+// Rows referencing a table with a condition are only included when the referenced row is included as well, so rows with an empty foreign key (like guest orders) are excluded.
+// Foreign keys over multiple columns are compared as a tuple of all columns.
+// A table is also restricted by a table depending on it, when that table is an unique extension deleted together with it (like order_customer of order).
+// Every table receives only conditions of tables which have been discovered before it, so cycles in the foreign keys are not followed.
+//
+// The values of the included rows are selected table by table and listed in the conditions of the next tables,
+// so a condition does not nest the conditions of all tables before it.
+func SubsetWhere(ctx context.Context, roots map[string]string, keys []ForeignKey, values SubsetValues) (map[string]string, error) {
+	rootTables := make([]string, 0, len(roots))
+
+	for table := range roots {
+		rootTables = append(rootTables, table)
+	}
+
+	sort.Strings(rootTables)
+
+	discovered := make(map[string]int)
+	queue := make([]string, 0)
+
+	for _, table := range rootTables {
+		discovered[table] = len(discovered)
+		queue = append(queue, table)
+	}
+
+	for len(queue) > 0 {
+		table := queue[0]
+		queue = queue[1:]
+
+		for _, key := range keys {
+			if key.Table == key.ReferencedTable {
+				continue
+			}
+
+			next := ""
+
+			if key.ReferencedTable == table {
+				next = key.Table
+			} else if key.Table == table && key.Unique && key.Cascade {
+				next = key.ReferencedTable
+			}
+
+			if next == "" {
+				continue
+			}
+
+			if _, ok := discovered[next]; ok {
+				continue
+			}
+
+			discovered[next] = len(discovered)
+			queue = append(queue, next)
+		}
+	}
+
+	tables := make([]string, 0, len(discovered))
+
+	for table := range discovered {
+		tables = append(tables, table)
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return discovered[tables[i]] < discovered[tables[j]]
+	})
+
+	where := make(map[string]string)
+
+	for _, table := range tables {
+		conditions := make([]string, 0)
+
+		if root, ok := roots[table]; ok {
+			conditions = append(conditions, fmt.Sprintf("(%s)", root))
+		}
+
+		for _, key := range keys {
+			if key.Table == key.ReferencedTable {
+				continue
+			}
+
+			if key.Table == table {
+				parentOrder, ok := discovered[key.ReferencedTable]
+
+				if !ok || parentOrder >= discovered[table] {
+					continue
+				}
+
+				included, err := values(ctx, key.ReferencedTable, key.ReferencedColumns, where[key.ReferencedTable])
+				if err != nil {
+					return nil, fmt.Errorf("could not select the subset of %s: %w", key.ReferencedTable, err)
+				}
+
+				conditions = append(conditions, inCondition(key.Columns, included))
+			}
+
+			if key.ReferencedTable == table && key.Unique && key.Cascade {
+				childOrder, ok := discovered[key.Table]
+
+				if !ok || childOrder >= discovered[table] {
+					continue
+				}
+
+				included, err := values(ctx, key.Table, key.Columns, where[key.Table])
+				if err != nil {
+					return nil, fmt.Errorf("could not select the subset of %s: %w", key.Table, err)
+				}
+
+				conditions = append(conditions, inCondition(key.ReferencedColumns, included))
+			}
+		}
+
+		if len(conditions) > 0 {
+			where[table] = strings.Join(conditions, " AND ")
+		}
+	}
+
+	return where, nil
+}
+
+// inCondition matches the columns against the values, no values match no row.
+func inCondition(columns []string, values []string) string {
+	if len(values) == 0 {
+		return "FALSE"
+	}
+
+	return fmt.Sprintf("%s IN (%s)", columnTuple(columns), strings.Join(values, ", "))
+}
+
+// QuerySubsetValues selects the values from the database. Numbers are written as they are, all other values as hex literals.
+func QuerySubsetValues(db *sql.DB) SubsetValues {
+	return func(ctx context.Context, table string, columns []string, where string) ([]string, error) {
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT DISTINCT %s FROM `%s` WHERE %s", columnList(columns), table, where))
+		if err != nil {
+			return nil, err
+		}
+
+		defer rows.Close()
+
+		types, err := rows.ColumnTypes()
+		if err != nil {
+			return nil, err
+		}
+
+		raw := make([]sql.RawBytes, len(columns))
+		dest := make([]interface{}, len(columns))
+
+		for i := range raw {
+			dest[i] = &raw[i]
+		}
+
+		values := make([]string, 0)
+
+		for rows.Next() {
+			if err := rows.Scan(dest...); err != nil {
+				return nil, err
+			}
+
+			if value, ok := subsetValue(raw, types); ok {
+				values = append(values, value)
+			}
+		}
+
+		return values, rows.Err()
+	}
+}
+
+// subsetValue formats the columns of a row as literal or tuple, it returns false when a column is NULL.
+func subsetValue(raw []sql.RawBytes, types []*sql.ColumnType) (string, bool) {
+	literals := make([]string, 0, len(raw))
+
+	for i, value := range raw {
+		if value == nil {
+			return "", false
+		}
+
+		literals = append(literals, sqlLiteral(value, types[i].DatabaseTypeName()))
+	}
+
+	if len(literals) == 1 {
+		return literals[0], true
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(literals, ", ")), true
+}
+
+func sqlLiteral(value []byte, databaseType string) string {
+	if strings.Contains(databaseType, "INT") || strings.Contains(databaseType, "DECIMAL") {
+		return string(value)
+	}
+
+	return fmt.Sprintf("X'%s'", hex.EncodeToString(value))
+}
+
+func columnList(columns []string) string {
+	quoted := make([]string, 0, len(columns))
+
+	for _, column := range columns {
+		quoted = append(quoted, fmt.Sprintf("`%s`", column))
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+func columnTuple(columns []string) string {
+	if len(columns) == 1 {
+		return columnList(columns)
+	}
+
+	return fmt.Sprintf("(%s)", columnList(columns))
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, column := range a {
+		if !slices.Contains(b, column) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,82 @@
+package dbdump
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSubsetValues returns the values of the tables and records the queried conditions.
+func fakeSubsetValues(data map[string][]string, queries map[string]string) SubsetValues {
+	return func(_ context.Context, table string, columns []string, where string) ([]string, error) {
+		key := fmt.Sprintf("%s.%s", table, strings.Join(columns, ","))
+		queries[key] = where
+
+		return data[key], nil
+	}
+}
+
+func TestSubsetWhere(t *testing.T) {
+	keys := []ForeignKey{
+		{Name: "fk.customer_address.customer_id", Table: "customer_address", Columns: []string{"customer_id"}, ReferencedTable: "customer", ReferencedColumns: []string{"id"}, Cascade: true},
+		{Name: "fk.customer.default_billing_address_id", Table: "customer", Columns: []string{"default_billing_address_id"}, ReferencedTable: "customer_address", ReferencedColumns: []string{"id"}},
+		{Name: "fk.order_customer.customer_id", Table: "order_customer", Columns: []string{"customer_id"}, ReferencedTable: "customer", ReferencedColumns: []string{"id"}},
+		{Name: "fk.order_customer.order_id", Table: "order_customer", Columns: []string{"order_id", "order_version_id"}, ReferencedTable: "order", ReferencedColumns: []string{"id", "version_id"}, Unique: true, Cascade: true},
+		{Name: "fk.order_line_item.order_id", Table: "order_line_item", Columns: []string{"order_id", "order_version_id"}, ReferencedTable: "order", ReferencedColumns: []string{"id", "version_id"}, Cascade: true},
+		{Name: "fk.order.currency_id", Table: "order", Columns: []string{"currency_id"}, ReferencedTable: "currency", ReferencedColumns: []string{"id"}},
+		{Name: "fk.category.parent_id", Table: "category", Columns: []string{"parent_id"}, ReferencedTable: "category", ReferencedColumns: []string{"id"}},
+	}
+
+	t.Run("follows dependent tables", func(t *testing.T) {
+		queries := map[string]string{}
+
+		where, err := SubsetWhere(context.Background(), map[string]string{"customer": "created_at > '2024-01-01'"}, keys, fakeSubsetValues(map[string][]string{
+			"customer.id": {"X'01'", "X'02'"},
+			"order_customer.order_id,order_version_id": {"(X'0a', X'0f')"},
+			"order.id,version_id":                      {"(X'0a', X'0f')"},
+		}, queries))
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"customer":         "(created_at > '2024-01-01')",
+			"customer_address": "`customer_id` IN (X'01', X'02')",
+			"order_customer":   "`customer_id` IN (X'01', X'02')",
+			"order":            "(`id`, `version_id`) IN ((X'0a', X'0f'))",
+			"order_line_item":  "(`order_id`, `order_version_id`) IN ((X'0a', X'0f'))",
+		}, where)
+
+		// the values are selected with the conditions of the table, not with the nested conditions of all tables before
+		assert.Equal(t, "`customer_id` IN (X'01', X'02')", queries["order_customer.order_id,order_version_id"])
+		assert.Equal(t, "(`id`, `version_id`) IN ((X'0a', X'0f'))", queries["order.id,version_id"])
+	})
+
+	t.Run("without included rows", func(t *testing.T) {
+		where, err := SubsetWhere(context.Background(), map[string]string{"customer": "1 = 0"}, keys, fakeSubsetValues(map[string][]string{}, map[string]string{}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "FALSE", where["customer_address"])
+	})
+
+	t.Run("ignores self references and referenced tables", func(t *testing.T) {
+		where, err := SubsetWhere(context.Background(), map[string]string{"category": "active = 1"}, keys, fakeSubsetValues(nil, map[string]string{}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"category": "(active = 1)"}, where)
+	})
+
+	t.Run("without roots", func(t *testing.T) {
+		where, err := SubsetWhere(context.Background(), map[string]string{}, keys, fakeSubsetValues(nil, map[string]string{}))
+
+		assert.NoError(t, err)
+		assert.Empty(t, where)
+	})
+}
+
+func TestSqlLiteral(t *testing.T) {
+	assert.Equal(t, "42", sqlLiteral([]byte("42"), "UNSIGNED BIGINT"))
+	assert.Equal(t, "X'0102'", sqlLiteral([]byte{1, 2}, "BINARY"))
+	assert.Equal(t, "X'6927'", sqlLiteral([]byte("i'"), "VARCHAR"))
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	secretMask = "******"
+	// shorter values would mask arbitrary parts of the log lines
+	minSecretLength = 4
+)
+
+var (
+	secretsLock     sync.RWMutex
+	secrets         = make(map[string]bool)
+	secretsReplacer = strings.NewReplacer()
+)
+
+// AddSecret registers a value, which is masked in all log lines. Values shorter than four characters are ignored.
+func AddSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+
+	if secrets[value] {
+		return
+	}
+
+	secrets[value] = true
+
+	values := make([]string, 0, len(secrets))
+
+	for secret := range secrets {
+		values = append(values, secret)
+	}
+
+	// longer secrets first, so a secret containing another one is masked completely
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+
+		return values[i] < values[j]
+	})
+
+	pairs := make([]string, 0, len(values)*2)
+
+	for _, secret := range values {
+		pairs = append(pairs, secret, secretMask)
+	}
+
+	secretsReplacer = strings.NewReplacer(pairs...)
+}
+
+// MaskSecrets replaces all registered secrets in the text.
+func MaskSecrets(text string) string {
+	secretsLock.RLock()
+	defer secretsLock.RUnlock()
+
+	return secretsReplacer.Replace(text)
+}
+
+// maskingCore masks the registered secrets in the message and the string fields of every entry.
+type maskingCore struct {
+	zapcore.Core
+}
+
+func newMaskingCore(core zapcore.Core) zapcore.Core {
+	return maskingCore{Core: core}
+}
+
+func (c maskingCore) With(fields []zapcore.Field) zapcore.Core {
+	return maskingCore{Core: c.Core.With(maskFields(fields))}
+}
+
+func (c maskingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+func (c maskingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = MaskSecrets(entry.Message)
+
+	return c.Core.Write(entry, maskFields(fields))
+}
+
+func maskFields(fields []zapcore.Field) []zapcore.Field {
+	masked := make([]zapcore.Field, len(fields))
+
+	for i, field := range fields {
+		switch field.Type {
+		case zapcore.StringType:
+			field.String = MaskSecrets(field.String)
+		case zapcore.ErrorType:
+			if err, ok := field.Interface.(error); ok {
+				field = zap.String(field.Key, MaskSecrets(err.Error()))
+			}
+		case zapcore.StringerType:
+			if stringer, ok := field.Interface.(interface{ String() string }); ok {
+				field = zap.String(field.Key, MaskSecrets(stringer.String()))
+			}
+		}
+
+		masked[i] = field
+	}
+
+	return masked
+}
@@ -0,0 +1,27 @@
+package logging
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	AddSecret("abc")
+	AddSecret("top-secret")
+	AddSecret("top-secret-token")
+
+	assert.Equal(t, "abc ****** ******", MaskSecrets("abc top-secret top-secret-token"))
+
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(newMaskingCore(core)).Sugar()
+
+	logger.With("token", "top-secret").Infow("login with top-secret", "error", errors.New("invalid top-secret"))
+
+	entry := logs.All()[0]
+	assert.Equal(t, "login with ******", entry.Message)
+	assert.Equal(t, map[string]interface{}{"token": "******", "error": "invalid ******"}, entry.ContextMap())
+}
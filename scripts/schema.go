@@ -3,14 +3,11 @@ package main
 import (
 	"encoding/json"
 	"github.com/FriendsOfShopware/shopware-cli/shop"
-	"github.com/invopop/jsonschema"
 	"os"
 )
 
 func generateProjectSchema() error {
-	r := new(jsonschema.Reflector)
-	r.FieldNameTag = "yaml"
-	r.RequiredFromJSONSchemaTags = true
+	r := shop.NewConfigSchemaReflector()
 
 	if err := r.AddGoComments("github.com/FriendsOfShopware/shopware-cli", "./shop"); err != nil {
 		return err
@@ -39,6 +39,13 @@ func NewShopClient(ctx context.Context, config *Config) (*adminSdk.Client, error
 		return nil, fmt.Errorf("admin-api is not enabled in config")
 	}
 
+	// the values used with the shop, the secrets of other sections and environments are not resolved
+	for _, section := range []interface{}{&config.URL, config.AdminApi, config.Sync} {
+		if err := ResolveSecretReferences(section); err != nil {
+			return nil, err
+		}
+	}
+
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{
 			MinVersion:         tls.VersionTLS12,
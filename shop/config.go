@@ -1,6 +1,7 @@
 package shop
 
 import (
+	"errors"
 	"fmt"
 	"github.com/invopop/jsonschema"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
@@ -25,7 +26,30 @@ type Config struct {
 	ConfigDump       *ConfigDump       `yaml:"dump,omitempty"`
 	Sync             *ConfigSync       `yaml:"sync,omitempty"`
 	ConfigDeployment *ConfigDeployment `yaml:"deployment,omitempty"`
-	foundConfig      bool
+	// Named environments, selected with --env. The values of an environment replace the values above
+	Environments map[string]ConfigEnvironment `yaml:"environments,omitempty"`
+	foundConfig  bool
+}
+
+type ConfigEnvironment struct {
+	// The URL of the Shopware instance
+	URL      string          `yaml:"url,omitempty"`
+	AdminApi *ConfigAdminApi `yaml:"admin_api,omitempty"`
+	Sync     *ConfigSync     `yaml:"sync,omitempty"`
+	// node is the environment as written in the file, so also false and empty values replace the base values
+	node *yaml.Node
+}
+
+func (e *ConfigEnvironment) UnmarshalYAML(value *yaml.Node) error {
+	type plain ConfigEnvironment
+
+	if err := value.Decode((*plain)(e)); err != nil {
+		return err
+	}
+
+	e.node = value
+
+	return nil
 }
 
 type ConfigBuild struct {
@@ -67,14 +91,60 @@ type ConfigDump struct {
 	Ignore []string `yaml:"ignore,omitempty"`
 	// Add an where condition to that table, schema is table name as key, and where statement as value
 	Where map[string]string `yaml:"where,omitempty"`
+	// When enabled, the where conditions are followed along the foreign keys, so only rows depending on the filtered rows are exported
+	Subset bool `yaml:"subset,omitempty"`
+	// Encrypts the dump with age
+	Encryption *ConfigDumpEncryption `yaml:"encryption,omitempty"`
+	// Named profiles, selected with project dump --profile. A profile replaces the rules above, only the encryption is used when the profile has none
+	Profiles map[string]ConfigDumpProfile `yaml:"profiles,omitempty"`
+}
+
+type ConfigDumpEncryption struct {
+	// age X25519 recipients (age1...) or files containing recipients
+	Recipients []string `yaml:"recipients,omitempty"`
+	// Passphrase to encrypt the dump with, use an environment variable like ${DUMP_PASSPHRASE}. Cannot be combined with recipients
+	Passphrase string `yaml:"passphrase,omitempty"`
+}
+
+type ConfigDumpProfile struct {
+	// Allows to rewrite single columns, perfect for GDPR compliance
+	Rewrite map[string]core.Rewrite `yaml:"rewrite,omitempty"`
+	// Only export the schema of these tables
+	NoData []string `yaml:"nodata,omitempty"`
+	// Ignore these tables from export
+	Ignore []string `yaml:"ignore,omitempty"`
+	// Add an where condition to that table, schema is table name as key, and where statement as value
+	Where map[string]string `yaml:"where,omitempty"`
+	// When enabled, the where conditions are followed along the foreign keys, so only rows depending on the filtered rows are exported
+	Subset bool `yaml:"subset,omitempty"`
+	// When enabled, the table list of --clean is included
+	Clean bool `yaml:"clean,omitempty"`
+	// When enabled, the anonymize presets of --anonymize are included
+	Anonymize bool `yaml:"anonymize,omitempty"`
+	// Encrypts the dump with age, replaces the encryption of the dump block
+	Encryption *ConfigDumpEncryption `yaml:"encryption,omitempty"`
 }
 
 type ConfigSync struct {
-	Enabled      *[]string          `yaml:"enabled,omitempty" jsonschema:"enum=system_config,enum=mail_template,enum=theme,enum=entity"`
+	Enabled      *[]string          `yaml:"enabled,omitempty" jsonschema:"enum=system_config,enum=mail_template,enum=theme,enum=entity,enum=snippet,enum=cms,enum=rule,enum=flow"`
 	Config       []ConfigSyncConfig `yaml:"config,omitempty"`
 	Theme        []ThemeConfig      `yaml:"theme,omitempty"`
 	MailTemplate []MailTemplate     `yaml:"mail_template,omitempty"`
 	Entity       []EntitySync       `yaml:"entity,omitempty"`
+	// Rules which entities are written to sync.entity by project config pull
+	EntityPull []EntityPullRule `yaml:"entity_pull,omitempty"`
+	// Groups of entities of the same type, managed groups delete records which are not configured
+	EntityGroups []EntitySyncGroup `yaml:"entity_groups,omitempty"`
+	// Glob patterns of YAML files with entity sync entries, relative to the project config
+	EntityPaths []string `yaml:"entity_paths,omitempty"`
+	// Storefront snippets per snippet set
+	Snippet []SnippetSync `yaml:"snippet,omitempty"`
+	// CMS layouts (Shopping Experiences), one YAML file per layout
+	Cms []CmsLayout `yaml:"cms,omitempty"`
+	// Rules of the Rule Builder, one YAML file per rule
+	Rule []RuleDefinition `yaml:"rule,omitempty"`
+	// Flows of the Flow Builder, one YAML file per flow
+	Flow []FlowDefinition `yaml:"flow,omitempty"`
 }
 
 type ConfigDeployment struct {
@@ -117,8 +187,13 @@ type ConfigDeployment struct {
 	} `yaml:"one-time-tasks"`
 }
 
-type ConfigDeploymentOverrides map[string]struct {
+type ConfigDeploymentOverrides map[string]ConfigDeploymentOverride
+
+type ConfigDeploymentOverride struct {
+	// The desired state of the extension
 	State string `yaml:"state"`
+	// Keep the data of the extension on uninstall
+	KeepUserData bool `yaml:"keepUserData"`
 }
 
 func (c ConfigDeploymentOverrides) JSONSchema() *jsonschema.Schema {
@@ -161,23 +236,69 @@ type MailTemplate struct {
 	Translations []MailTemplateTranslation `yaml:"translations"`
 }
 
+type SnippetSync struct {
+	// Name of the snippet set
+	Set string `yaml:"set" jsonschema:"required"`
+	// JSON or YAML file with the snippets, keyed by translation key
+	File string `yaml:"file" jsonschema:"required"`
+}
+
+type CmsLayout struct {
+	// YAML file of the layout with its sections, blocks and slots
+	File string `yaml:"file" jsonschema:"required"`
+}
+
+type RuleDefinition struct {
+	// YAML file of the rule with its condition tree
+	File string `yaml:"file" jsonschema:"required"`
+}
+
+type FlowDefinition struct {
+	// YAML file of the flow with its sequence tree
+	File string `yaml:"file" jsonschema:"required"`
+}
+
 type EntitySync struct {
 	Entity  string                 `yaml:"entity"`
 	Exists  *[]EntitySyncFilter    `yaml:"exists,omitempty"`
 	Payload map[string]interface{} `yaml:"payload"`
 }
 
+type EntitySyncGroup struct {
+	// Name of the group
+	Name string `yaml:"name" jsonschema:"required"`
+	// The entity of the records in this group
+	Entity string `yaml:"entity" jsonschema:"required"`
+	// When enabled, records matching the scope which are not configured in this group are deleted
+	Managed bool `yaml:"managed,omitempty"`
+	// Criteria filter of the records owned by this group, all records of the entity when empty
+	Scope *[]EntitySyncFilter `yaml:"scope,omitempty"`
+	// The records of this group, the entity defaults to the entity of the group
+	Entities []EntitySync `yaml:"entities,omitempty"`
+}
+
+type EntityPullRule struct {
+	// The entity to pull, like tax or salutation
+	Entity string `yaml:"entity" jsonschema:"required"`
+	// Criteria filter to select the records to pull
+	Filter *[]EntitySyncFilter `yaml:"filter,omitempty"`
+	// Fields to write into the payload, all writable fields are used when empty
+	Fields []string `yaml:"fields,omitempty"`
+	// Fields used for the generated exists filter, defaults to id
+	Identifier []string `yaml:"identifier,omitempty"`
+}
+
 type EntitySyncFilter struct {
 	// The type of filter
-	Type string `yaml:"type" jsonschema:"required,enum=equals,enum=multi,enum=contains,enum=prefix,enum=suffix,enum=not,enum=range,enum=until,enum=equalsAll,enum=equalsAny"`
+	Type string `yaml:"type" json:"type" jsonschema:"required,enum=equals,enum=multi,enum=contains,enum=prefix,enum=suffix,enum=not,enum=range,enum=until,enum=equalsAll,enum=equalsAny"`
 	// The field to filter on
-	Field string `yaml:"field" jsonschema:"required"`
+	Field string `yaml:"field" json:"field" jsonschema:"required"`
 	// The actual filter value
-	Value interface{} `yaml:"value"`
+	Value interface{} `yaml:"value" json:"value"`
 	// The operator to use for multiple filters
-	Operator *string `yaml:"operator,omitempty" jsonschema:"enum=AND,enum=OR,enum=XOR"`
+	Operator *string `yaml:"operator,omitempty" json:"operator,omitempty" jsonschema:"enum=AND,enum=OR,enum=XOR"`
 	// The filters to apply, when type set to multi
-	Queries *[]EntitySyncFilter `yaml:"queries,omitempty"`
+	Queries *[]EntitySyncFilter `yaml:"queries,omitempty" json:"queries,omitempty"`
 }
 
 func (s EntitySyncFilter) JSONSchema() *jsonschema.Schema {
@@ -202,23 +323,34 @@ func (s EntitySyncFilter) JSONSchema() *jsonschema.Schema {
 		Enum: []interface{}{"AND", "OR", "XOR"},
 	})
 
+	properties.Set("queries", &jsonschema.Schema{
+		Type:        "array",
+		Description: "The filters to apply, when type set to multi",
+		Items:       &jsonschema.Schema{Ref: "#/$defs/EntitySyncFilter"},
+	})
+
 	ifProperties := orderedmap.New[string, *jsonschema.Schema]()
 	ifProperties.Set("type", &jsonschema.Schema{
 		Const: "multi",
 	})
 
 	return &jsonschema.Schema{
-		Type:       "object",
-		Title:      "Entity Sync Filter",
-		Properties: properties,
-		Required:   []string{"type", "field"},
+		Type:                 "object",
+		Title:                "Entity Sync Filter",
+		Properties:           properties,
+		AdditionalProperties: jsonschema.FalseSchema,
+		Required:             []string{"type"},
 		AllOf: []*jsonschema.Schema{
 			{
 				If: &jsonschema.Schema{
 					Properties: ifProperties,
-					Then: &jsonschema.Schema{
-						Required: []string{"type", "queries"},
-					},
+					Required:   []string{"type"},
+				},
+				Then: &jsonschema.Schema{
+					Required: []string{"queries"},
+				},
+				Else: &jsonschema.Schema{
+					Required: []string{"field"},
 				},
 			},
 		},
@@ -259,7 +391,12 @@ func ReadConfig(fileName string, allowFallback bool) (*Config, error) {
 	config.foundConfig = true
 
 	substitutedConfig := os.ExpandEnv(string(fileHandle))
-	err = yaml.Unmarshal([]byte(substitutedConfig), &config)
+	err = decodeConfig(fileName, []byte(substitutedConfig), config)
+
+	var problems ConfigErrors
+	if errors.As(err, &problems) {
+		return nil, problems
+	}
 
 	if len(config.AdditionalConfigs) > 0 {
 		for _, additionalConfigFile := range config.AdditionalConfigs {
@@ -289,6 +426,10 @@ const (
 	SyncOptionMailTemplate = "mail_template"
 	SyncOptionSystemConfig = "system_config"
 	SyncOptionTheme        = "theme"
+	SyncOptionSnippet      = "snippet"
+	SyncOptionCms          = "cms"
+	SyncOptionRule         = "rule"
+	SyncOptionFlow         = "flow"
 )
 
 func fillEmptyConfig(c *Config) *Config {
@@ -303,6 +444,31 @@ func fillEmptyConfig(c *Config) *Config {
 	return c
 }
 
+// UseEnvironment merges the values of the named environment into the config.
+func (c *Config) UseEnvironment(name string) error {
+	environment, ok := c.Environments[name]
+	if !ok {
+		return fmt.Errorf("environment %q is not configured", name)
+	}
+
+	node := environment.node
+
+	if node == nil {
+		node = &yaml.Node{}
+
+		if err := node.Encode(environment); err != nil {
+			return fmt.Errorf("error while merging environment %s: %s", name, err.Error())
+		}
+	}
+
+	// decoding into the config only replaces the values set in the environment, maps are merged and lists are replaced
+	if err := node.Decode(c); err != nil {
+		return fmt.Errorf("error while merging environment %s: %s", name, err.Error())
+	}
+
+	return nil
+}
+
 func (c Config) IsFallback() bool {
 	return !c.foundConfig
 }
@@ -0,0 +1,190 @@
+package shop
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+)
+
+const (
+	secretFilePrefix = "file://"
+	secretExecPrefix = "exec://"
+)
+
+var (
+	resolvedSecretsLock sync.Mutex
+	// resolvedSecrets caches the values of the secret references, so each command runs only once
+	resolvedSecrets = make(map[string]string)
+)
+
+// decodeConfig validates and decodes the YAML into the config. SOPS encrypted values are decrypted before.
+// Secret references are kept, they are resolved with ResolveSecretReferences when the values are used.
+func decodeConfig(fileName string, content []byte, config *Config) error {
+	var document yaml.Node
+
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return err
+	}
+
+	if document.Kind == 0 {
+		return nil
+	}
+
+	if err := decryptSopsDocument(&document); err != nil {
+		return err
+	}
+
+	if problems := validateConfigDocument(fileName, &document); len(problems) > 0 {
+		return problems
+	}
+
+	return document.Decode(config)
+}
+
+func isSecretReference(value string) bool {
+	return strings.HasPrefix(value, secretFilePrefix) || strings.HasPrefix(value, secretExecPrefix)
+}
+
+// ResolveSecretReferences replaces all strings like `file:///run/secrets/x` or `exec://pass show shop/prod` in the value with the secret.
+// The value has to be a pointer, like a section of the config. Only the references of the given value are resolved, so commands run only when their value is used.
+func ResolveSecretReferences(value interface{}) error {
+	return resolveSecretValue(reflect.ValueOf(value))
+}
+
+func resolveSecretValue(value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+
+		return resolveSecretValue(value.Elem())
+	case reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+
+		if reference, ok := value.Interface().(string); ok {
+			if !isSecretReference(reference) || !value.CanSet() {
+				return nil
+			}
+
+			secret, err := resolveSecret(reference)
+			if err != nil {
+				return err
+			}
+
+			value.Set(reflect.ValueOf(secret))
+
+			return nil
+		}
+
+		return resolveSecretValue(value.Elem())
+	case reflect.String:
+		if !isSecretReference(value.String()) || !value.CanSet() {
+			return nil
+		}
+
+		secret, err := resolveSecret(value.String())
+		if err != nil {
+			return err
+		}
+
+		value.SetString(secret)
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if !value.Type().Field(i).IsExported() {
+				continue
+			}
+
+			if err := resolveSecretValue(value.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := resolveSecretValue(value.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+
+		for iter.Next() {
+			// map values are not addressable, the resolved copy replaces them
+			item := reflect.New(value.Type().Elem()).Elem()
+			item.Set(iter.Value())
+
+			if err := resolveSecretValue(item); err != nil {
+				return err
+			}
+
+			value.SetMapIndex(iter.Key(), item)
+		}
+	}
+
+	return nil
+}
+
+func resolveSecret(reference string) (string, error) {
+	resolvedSecretsLock.Lock()
+	defer resolvedSecretsLock.Unlock()
+
+	if secret, ok := resolvedSecrets[reference]; ok {
+		return secret, nil
+	}
+
+	var secret string
+
+	switch {
+	case strings.HasPrefix(reference, secretFilePrefix):
+		path := strings.TrimPrefix(reference, secretFilePrefix)
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("cannot read secret %s: %w", reference, err)
+		}
+
+		secret = string(content)
+	case strings.HasPrefix(reference, secretExecPrefix):
+		command := strings.TrimPrefix(reference, secretExecPrefix)
+
+		var stderr bytes.Buffer
+
+		secretCmd := exec.Command("sh", "-c", command)
+		secretCmd.Stderr = &stderr
+
+		output, err := secretCmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("cannot resolve secret %s: %w: %s", reference, err, strings.TrimSpace(stderr.String()))
+		}
+
+		secret = string(output)
+	}
+
+	// files and command outputs usually end with a newline, which is not part of the secret
+	secret = strings.TrimRight(secret, "\r\n")
+
+	logging.AddSecret(secret)
+	resolvedSecrets[reference] = secret
+
+	return secret, nil
+}
+
+// lookupResolvedSecret returns the value of an already resolved secret reference.
+func lookupResolvedSecret(reference string) (string, bool) {
+	resolvedSecretsLock.Lock()
+	defer resolvedSecretsLock.Unlock()
+
+	secret, ok := resolvedSecrets[reference]
+
+	return secret, ok
+}
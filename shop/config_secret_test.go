@@ -0,0 +1,203 @@
+package shop
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+)
+
+func TestSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "client_secret")
+	marker := filepath.Join(dir, "executed")
+	file := filepath.Join(dir, ".shopware-project.yml")
+
+	assert.NoError(t, os.WriteFile(secretFile, []byte("file-secret\n"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(file, []byte(`url: http://localhost:8000
+admin_api:
+  client_id: local
+  client_secret: file://`+secretFile+`
+  password: exec://touch `+marker+` && echo exec-secret
+sync:
+  config:
+    - settings:
+        core.mailerSettings.password: exec://echo mailer-secret
+        core.mailerSettings.port: 25
+`), os.ModePerm))
+
+	cfg, err := ReadConfig(file, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "exec://touch "+marker+" && echo exec-secret", cfg.AdminApi.Password)
+	assert.NoFileExists(t, marker, "commands run only when the value is used")
+
+	assert.NoError(t, ResolveSecretReferences(cfg.AdminApi))
+	assert.NoError(t, ResolveSecretReferences(cfg.Sync))
+
+	assert.Equal(t, "file-secret", cfg.AdminApi.ClientSecret)
+	assert.Equal(t, "exec-secret", cfg.AdminApi.Password)
+	assert.FileExists(t, marker)
+	assert.Equal(t, map[string]interface{}{"core.mailerSettings.password": "mailer-secret", "core.mailerSettings.port": 25}, cfg.Sync.Config[0].Settings)
+	assert.Equal(t, "token ******", logging.MaskSecrets("token file-secret"))
+
+	t.Run("missing files fail", func(t *testing.T) {
+		_, err := resolveSecret("file://" + filepath.Join(dir, "missing"))
+
+		assert.ErrorContains(t, err, "cannot read secret")
+	})
+
+	t.Run("failing commands fail", func(t *testing.T) {
+		_, err := resolveSecret("exec://echo denied >&2 && exit 1")
+
+		assert.ErrorContains(t, err, "denied")
+	})
+}
+
+func TestSopsDecryption(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+
+	t.Setenv("SOPS_AGE_KEY", identity.String())
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+
+	file := filepath.Join(t.TempDir(), "secrets.yml")
+	content := encryptSopsTestFile(t, identity.Recipient(), "sops-client-secret", 8000)
+
+	assert.NoError(t, os.WriteFile(file, content, os.ModePerm))
+
+	cfg, err := ReadConfig(file, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "sops-client-secret", cfg.AdminApi.ClientSecret)
+	assert.Equal(t, "http://localhost:8000", cfg.URL)
+	assert.True(t, cfg.AdminApi.DisableSSLCheck)
+	assert.Equal(t, "******", logging.MaskSecrets("sops-client-secret"))
+
+	t.Run("modified values fail the mac check", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(file, bytes.Replace(content, []byte("client_id: local"), []byte("client_id: changed"), 1), os.ModePerm))
+
+		_, err := ReadConfig(file, false)
+
+		assert.ErrorContains(t, err, "mac does not match")
+	})
+
+	t.Run("other keys cannot decrypt", func(t *testing.T) {
+		other, err := age.GenerateX25519Identity()
+		assert.NoError(t, err)
+
+		t.Setenv("SOPS_AGE_KEY", other.String())
+
+		assert.NoError(t, os.WriteFile(file, content, os.ModePerm))
+
+		_, err = ReadConfig(file, false)
+
+		assert.ErrorContains(t, err, "none of the local age keys")
+	})
+}
+
+func TestSopsDecryptionOfSopsFile(t *testing.T) {
+	// testdata/sops/secrets.yml has been encrypted by the sops CLI with the age key of testdata/sops/key.txt
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", filepath.Join("testdata", "sops", "key.txt"))
+
+	cfg, err := ReadConfig(filepath.Join("testdata", "sops", "secrets.yml"), false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "http://localhost:8000", cfg.URL)
+	assert.Equal(t, "local", cfg.AdminApi.ClientId)
+	assert.Equal(t, "sops-client-secret", cfg.AdminApi.ClientSecret)
+	assert.True(t, cfg.AdminApi.DisableSSLCheck)
+	assert.Equal(t, map[string]interface{}{
+		"core.basicInformation.shopName": "Demo Shop",
+		"core.listing.productsPerPage":   24,
+		"core.basicInformation.taxRate":  19.5,
+	}, cfg.Sync.Config[0].Settings)
+}
+
+// encryptSopsTestFile builds a config file the way sops encrypts it with an age recipient.
+func encryptSopsTestFile(t *testing.T, recipient age.Recipient, secret string, port int) []byte {
+	t.Helper()
+
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+
+	encrypt := func(value, valueType, additionalData string) string {
+		block, err := aes.NewCipher(key)
+		assert.NoError(t, err)
+
+		gcm, err := cipher.NewGCMWithNonceSize(block, 32)
+		assert.NoError(t, err)
+
+		iv := make([]byte, 32)
+		_, err = rand.Read(iv)
+		assert.NoError(t, err)
+
+		sealed := gcm.Seal(nil, iv, []byte(value), []byte(additionalData))
+		data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+		return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+			base64.StdEncoding.EncodeToString(data),
+			base64.StdEncoding.EncodeToString(iv),
+			base64.StdEncoding.EncodeToString(tag),
+			valueType,
+		)
+	}
+
+	url := fmt.Sprintf("http://localhost:%d", port)
+	lastModified := "2024-01-01T00:00:00Z"
+
+	mac := sha512.New()
+	mac.Write([]byte(url))
+	mac.Write([]byte("local"))
+	mac.Write([]byte(secret))
+	mac.Write([]byte("True"))
+
+	var encryptedKey bytes.Buffer
+
+	armorWriter := armor.NewWriter(&encryptedKey)
+	ageWriter, err := age.Encrypt(armorWriter, recipient)
+	assert.NoError(t, err)
+
+	_, err = ageWriter.Write(key)
+	assert.NoError(t, err)
+	assert.NoError(t, ageWriter.Close())
+	assert.NoError(t, armorWriter.Close())
+
+	return []byte(fmt.Sprintf(`url: %s
+admin_api:
+  client_id: local
+  client_secret: %s
+  disable_ssl_check: %s
+sops:
+  age:
+    - recipient: %s
+      enc: |
+        %s
+  lastmodified: "%s"
+  mac: %s
+  version: 3.9.0
+`,
+		encrypt(url, "str", "url:"),
+		encrypt(secret, "str", "admin_api:client_secret:"),
+		encrypt("True", "bool", "admin_api:disable_ssl_check:"),
+		recipient,
+		strings.ReplaceAll(strings.TrimSpace(encryptedKey.String()), "\n", "\n        "),
+		lastModified,
+		encrypt(fmt.Sprintf("%X", mac.Sum(nil)), "str", lastModified),
+	))
+}
@@ -0,0 +1,269 @@
+package shop
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+
+	"github.com/FriendsOfShopware/shopware-cli/logging"
+)
+
+const sopsMetadataKey = "sops"
+
+var sopsValuePattern = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+type sopsMetadata struct {
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	LastModified     string `yaml:"lastmodified"`
+	Mac              string `yaml:"mac"`
+	MacOnlyEncrypted bool   `yaml:"mac_only_encrypted"`
+}
+
+// decryptSopsDocument decrypts the values of a file encrypted by SOPS with age and verifies its MAC. Other documents are not changed.
+func decryptSopsDocument(document *yaml.Node) error {
+	if len(document.Content) == 0 {
+		return nil
+	}
+
+	root := document.Content[0]
+
+	metadataNode := mappingValue(root, sopsMetadataKey)
+	if metadataNode == nil {
+		return nil
+	}
+
+	var metadata sopsMetadata
+
+	if err := metadataNode.Decode(&metadata); err != nil {
+		return fmt.Errorf("invalid sops metadata: %w", err)
+	}
+
+	if len(metadata.Age) == 0 {
+		return fmt.Errorf("the file is encrypted with sops without an age recipient, only age is supported")
+	}
+
+	key, err := sopsDataKey(metadata)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == sopsMetadataKey {
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+
+			break
+		}
+	}
+
+	mac := sha512.New()
+
+	if err := decryptSopsNode(root, nil, key, mac, metadata.MacOnlyEncrypted); err != nil {
+		return err
+	}
+
+	match := sopsValuePattern.FindStringSubmatch(metadata.Mac)
+	if match == nil {
+		return fmt.Errorf("the sops metadata has no valid mac")
+	}
+
+	expected, err := decryptSopsValue(match, key, metadata.LastModified)
+	if err != nil {
+		return fmt.Errorf("cannot decrypt the sops mac: %w", err)
+	}
+
+	if !strings.EqualFold(string(expected), fmt.Sprintf("%X", mac.Sum(nil))) {
+		return fmt.Errorf("the sops mac does not match, the file has been modified")
+	}
+
+	return nil
+}
+
+// decryptSopsNode decrypts all values in place. The path of the keys is the additional data of each value like SOPS does.
+func decryptSopsNode(node *yaml.Node, path []string, key []byte, mac hash.Hash, macOnlyEncrypted bool) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childPath := append(append([]string{}, path...), node.Content[i].Value)
+
+			if err := decryptSopsNode(node.Content[i+1], childPath, key, mac, macOnlyEncrypted); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if err := decryptSopsNode(item, path, key, mac, macOnlyEncrypted); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		match := sopsValuePattern.FindStringSubmatch(node.Value)
+		if match == nil {
+			if !macOnlyEncrypted {
+				var value interface{}
+
+				if err := node.Decode(&value); err != nil {
+					return err
+				}
+
+				mac.Write(sopsMacBytes(value))
+			}
+
+			return nil
+		}
+
+		plaintext, err := decryptSopsValue(match, key, strings.Join(path, ":")+":")
+		if err != nil {
+			return fmt.Errorf("cannot decrypt %s: %w", strings.Join(path, "."), err)
+		}
+
+		mac.Write(plaintext)
+
+		node.Value = string(plaintext)
+		node.Style = 0
+
+		switch match[4] {
+		case "int":
+			node.Tag = "!!int"
+		case "float":
+			node.Tag = "!!float"
+		case "bool":
+			node.Tag = "!!bool"
+			node.Value = strings.ToLower(node.Value)
+		default:
+			node.Tag = "!!str"
+			node.Style = yaml.DoubleQuotedStyle
+
+			logging.AddSecret(node.Value)
+		}
+	}
+
+	return nil
+}
+
+func decryptSopsValue(match []string, key []byte, additionalData string) ([]byte, error) {
+	parts := make([][]byte, 3)
+
+	for i, encoded := range match[1:4] {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		parts[i] = decoded
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(parts[1]))
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Open(nil, parts[1], append(parts[0], parts[2]...), []byte(additionalData))
+}
+
+// sopsMacBytes converts an unencrypted value like SOPS does for the MAC.
+func sopsMacBytes(value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return []byte(v)
+	case int:
+		return []byte(strconv.Itoa(v))
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		if v {
+			return []byte("True")
+		}
+
+		return []byte("False")
+	}
+
+	return []byte(fmt.Sprint(value))
+}
+
+// sopsDataKey decrypts the data key of the file with one of the local age identities.
+func sopsDataKey(metadata sopsMetadata) ([]byte, error) {
+	identities, err := sopsAgeIdentities()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range metadata.Age {
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(entry.Enc)), identities...)
+		if err != nil {
+			continue
+		}
+
+		return io.ReadAll(r)
+	}
+
+	return nil, fmt.Errorf("cannot decrypt the sops data key, none of the local age keys is a recipient of the file")
+}
+
+// sopsAgeIdentities reads the age keys from SOPS_AGE_KEY, SOPS_AGE_KEY_FILE or the default key file of SOPS.
+func sopsAgeIdentities() ([]age.Identity, error) {
+	identities := make([]age.Identity, 0)
+
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		parsed, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse SOPS_AGE_KEY: %w", err)
+		}
+
+		identities = append(identities, parsed...)
+	}
+
+	keyFile := os.Getenv("SOPS_AGE_KEY_FILE")
+	explicit := keyFile != ""
+
+	if !explicit {
+		if configDir, err := os.UserConfigDir(); err == nil {
+			keyFile = filepath.Join(configDir, "sops", "age", "keys.txt")
+		}
+	}
+
+	if keyFile != "" {
+		content, err := os.ReadFile(keyFile)
+
+		switch {
+		case err == nil:
+			parsed, err := age.ParseIdentities(strings.NewReader(string(content)))
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse age key file %s: %w", keyFile, err)
+			}
+
+			identities = append(identities, parsed...)
+		case explicit || !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("cannot read age key file: %w", err)
+		}
+	}
+
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("the file is encrypted with sops, set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE to decrypt it")
+	}
+
+	return identities, nil
+}
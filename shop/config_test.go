@@ -0,0 +1,60 @@
+package shop
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseEnvironment(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".shopware-project.yml")
+
+	assert.NoError(t, os.WriteFile(file, []byte(`url: http://localhost:8000
+admin_api:
+  client_id: local
+  client_secret: local-secret
+  password: local-password
+  disable_ssl_check: true
+sync:
+  snippet:
+    - set: BASE de-DE
+      file: snippets/de-DE.json
+  theme:
+    - name: Storefront
+environments:
+  staging:
+    url: https://staging.example.com
+    admin_api:
+      client_id: staging
+      password: ""
+      disable_ssl_check: false
+    sync:
+      snippet:
+        - set: BASE de-DE
+          file: snippets/staging.json
+`), os.ModePerm))
+
+	t.Run("environment values replace the base values", func(t *testing.T) {
+		cfg, err := ReadConfig(file, false)
+		assert.NoError(t, err)
+
+		assert.NoError(t, cfg.UseEnvironment("staging"))
+
+		assert.Equal(t, "https://staging.example.com", cfg.URL)
+		assert.Equal(t, "staging", cfg.AdminApi.ClientId)
+		assert.Equal(t, "local-secret", cfg.AdminApi.ClientSecret)
+		assert.Empty(t, cfg.AdminApi.Password)
+		assert.False(t, cfg.AdminApi.DisableSSLCheck)
+		assert.Equal(t, []SnippetSync{{Set: "BASE de-DE", File: "snippets/staging.json"}}, cfg.Sync.Snippet)
+		assert.Len(t, cfg.Sync.Theme, 1)
+	})
+
+	t.Run("unknown environment", func(t *testing.T) {
+		cfg, err := ReadConfig(file, false)
+		assert.NoError(t, err)
+
+		assert.ErrorContains(t, cfg.UseEnvironment("production"), `environment "production" is not configured`)
+	})
+}
@@ -0,0 +1,426 @@
+package shop
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigError is a problem at a position of a config file.
+type ConfigError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// ConfigErrors are all problems found in a config file.
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	messages := make([]string, 0, len(e))
+
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// NewConfigSchemaReflector returns the reflector generating the JSON schema of the project config.
+func NewConfigSchemaReflector() *jsonschema.Reflector {
+	r := new(jsonschema.Reflector)
+	r.FieldNameTag = "yaml"
+	r.RequiredFromJSONSchemaTags = true
+
+	return r
+}
+
+var configSchema = sync.OnceValue(func() *jsonschema.Schema {
+	return NewConfigSchemaReflector().Reflect(&Config{})
+})
+
+// ValidateConfig validates the config file and all included files against the JSON schema of the project config.
+func ValidateConfig(fileName string) (ConfigErrors, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(content))), &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	if document.Kind == 0 {
+		return nil, nil
+	}
+
+	if err := decryptSopsDocument(&document); err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	problems := validateConfigDocument(fileName, &document)
+
+	include := mappingValue(document.Content[0], "include")
+	if include == nil || include.Kind != yaml.SequenceNode {
+		return problems, nil
+	}
+
+	for _, item := range include.Content {
+		if item.Kind != yaml.ScalarNode {
+			continue
+		}
+
+		included, err := ValidateConfig(item.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		problems = append(problems, included...)
+	}
+
+	return problems, nil
+}
+
+func validateConfigDocument(fileName string, document *yaml.Node) ConfigErrors {
+	schema := configSchema()
+
+	v := &schemaValidator{file: fileName, definitions: schema.Definitions}
+	v.validate(document, schema, "")
+
+	return v.errors
+}
+
+// schemaValidator validates a YAML node tree against the subset of JSON schema generated from the config structs.
+type schemaValidator struct {
+	file        string
+	definitions jsonschema.Definitions
+	errors      ConfigErrors
+	// reported are the problems by position, nodes merged into several mappings are only reported once
+	reported map[string]bool
+}
+
+func (v *schemaValidator) addError(node *yaml.Node, path, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+
+	position := fmt.Sprintf("%d:%d:%s", node.Line, node.Column, message)
+	if v.reported[position] {
+		return
+	}
+
+	if v.reported == nil {
+		v.reported = make(map[string]bool)
+	}
+
+	v.reported[position] = true
+
+	if path != "" {
+		message = path + ": " + message
+	}
+
+	v.errors = append(v.errors, ConfigError{File: v.file, Line: node.Line, Column: node.Column, Message: message})
+}
+
+func (v *schemaValidator) resolve(schema *jsonschema.Schema) *jsonschema.Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = v.definitions[strings.TrimPrefix(schema.Ref, "#/$defs/")]
+	}
+
+	return schema
+}
+
+// matches reports whether the node is valid for the schema, without recording errors.
+func (v *schemaValidator) matches(node *yaml.Node, schema *jsonschema.Schema) bool {
+	sub := &schemaValidator{file: v.file, definitions: v.definitions}
+	sub.validate(node, schema, "")
+
+	return len(sub.errors) == 0
+}
+
+func (v *schemaValidator) validate(node *yaml.Node, schema *jsonschema.Schema, path string) {
+	schema = v.resolve(schema)
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			v.validate(child, schema, path)
+		}
+
+		return
+	case yaml.AliasNode:
+		v.validate(node.Alias, schema, path)
+
+		return
+	}
+
+	if schema == nil || schema == jsonschema.TrueSchema {
+		return
+	}
+
+	// null is decoded as zero value of any type
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+		return
+	}
+
+	if !v.validateType(node, schema, path) {
+		return
+	}
+
+	if len(schema.Enum) > 0 && !containsSchemaValue(schema.Enum, node.Value) {
+		values := make([]string, 0, len(schema.Enum))
+
+		for _, value := range schema.Enum {
+			values = append(values, fmt.Sprint(value))
+		}
+
+		v.addError(node, path, "invalid value %q, allowed values are %s", node.Value, strings.Join(values, ", "))
+	}
+
+	if schema.Const != nil && !containsSchemaValue([]interface{}{schema.Const}, node.Value) {
+		v.addError(node, path, "invalid value %q, expected %v", node.Value, schema.Const)
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		v.validateMapping(node, schema, path)
+	case yaml.SequenceNode:
+		if schema.Items != nil {
+			for i, item := range node.Content {
+				v.validate(item, schema.Items, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+
+	for _, sub := range schema.AllOf {
+		v.validate(node, sub, path)
+
+		if sub.If == nil {
+			continue
+		}
+
+		if v.matches(node, sub.If) {
+			if sub.Then != nil {
+				v.validate(node, sub.Then, path)
+			}
+		} else if sub.Else != nil {
+			v.validate(node, sub.Else, path)
+		}
+	}
+}
+
+func (v *schemaValidator) validateType(node *yaml.Node, schema *jsonschema.Schema, path string) bool {
+	var valid bool
+
+	switch schema.Type {
+	case "":
+		return true
+	case "object":
+		valid = node.Kind == yaml.MappingNode
+	case "array":
+		valid = node.Kind == yaml.SequenceNode
+	case "string":
+		// YAML decodes any scalar into a string
+		valid = node.Kind == yaml.ScalarNode
+	case "integer":
+		valid = node.Kind == yaml.ScalarNode && node.ShortTag() == "!!int"
+	case "number":
+		valid = node.Kind == yaml.ScalarNode && (node.ShortTag() == "!!int" || node.ShortTag() == "!!float")
+	case "boolean":
+		valid = node.Kind == yaml.ScalarNode && node.ShortTag() == "!!bool"
+	default:
+		return true
+	}
+
+	if !valid {
+		v.addError(node, path, "expected %s, got %s", describeSchemaType(schema.Type), describeYamlNode(node))
+	}
+
+	return valid
+}
+
+func (v *schemaValidator) validateMapping(node *yaml.Node, schema *jsonschema.Schema, path string) {
+	keys := make(map[string]bool)
+	pairs := mappingPairs(node)
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		keyNode, valueNode := pairs[i], pairs[i+1]
+		key := keyNode.Value
+		keys[key] = true
+
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+
+		if schema.Properties != nil {
+			if property, ok := schema.Properties.Get(key); ok {
+				v.validate(valueNode, property, childPath)
+
+				continue
+			}
+		}
+
+		switch schema.AdditionalProperties {
+		case nil, jsonschema.TrueSchema:
+		case jsonschema.FalseSchema:
+			message := fmt.Sprintf("unknown key %q", key)
+
+			if suggestion := suggestSchemaProperty(schema, key); suggestion != "" {
+				message += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+
+			v.addError(keyNode, path, "%s", message)
+		default:
+			v.validate(valueNode, schema.AdditionalProperties, childPath)
+		}
+	}
+
+	for _, required := range schema.Required {
+		if !keys[required] {
+			v.addError(node, path, "missing required key %q", required)
+		}
+	}
+}
+
+// mappingPairs returns the keys and values of the mapping with the merge keys (<<) expanded. The keys of the mapping take precedence over merged keys.
+func mappingPairs(node *yaml.Node) []*yaml.Node {
+	pairs := make([]*yaml.Node, 0, len(node.Content))
+	merged := make([]*yaml.Node, 0)
+	keys := make(map[string]bool)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+
+		if keyNode.Kind != yaml.ScalarNode || keyNode.ShortTag() != "!!merge" {
+			pairs = append(pairs, keyNode, valueNode)
+			keys[keyNode.Value] = true
+
+			continue
+		}
+
+		sources := []*yaml.Node{valueNode}
+
+		if resolved := resolveYamlAlias(valueNode); resolved.Kind == yaml.SequenceNode {
+			sources = resolved.Content
+		}
+
+		for _, source := range sources {
+			if resolved := resolveYamlAlias(source); resolved.Kind == yaml.MappingNode {
+				merged = append(merged, mappingPairs(resolved)...)
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(merged); i += 2 {
+		if !keys[merged[i].Value] {
+			pairs = append(pairs, merged[i], merged[i+1])
+			keys[merged[i].Value] = true
+		}
+	}
+
+	return pairs
+}
+
+func resolveYamlAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	return node
+}
+
+func containsSchemaValue(values []interface{}, value string) bool {
+	for _, allowed := range values {
+		if fmt.Sprint(allowed) == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func describeSchemaType(schemaType string) string {
+	switch schemaType {
+	case "object":
+		return "a map"
+	case "array":
+		return "a list"
+	case "integer":
+		return "an integer"
+	}
+
+	return "a " + schemaType
+}
+
+func describeYamlNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a map"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+
+	return fmt.Sprintf("%q", node.Value)
+}
+
+// suggestSchemaProperty returns the most similar property for a misspelled key.
+func suggestSchemaProperty(schema *jsonschema.Schema, key string) string {
+	if schema.Properties == nil {
+		return ""
+	}
+
+	candidates := make([]string, 0)
+
+	for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		if levenshteinDistance(key, pair.Key) <= max(2, len(key)/4) {
+			candidates = append(candidates, pair.Key)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return levenshteinDistance(key, candidates[i]) < levenshteinDistance(key, candidates[j])
+	})
+
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	return candidates[0]
+}
+
+func levenshteinDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,141 @@
+package shop
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, ".shopware-project.yml")
+	include := filepath.Join(dir, "sync.yml")
+
+	assert.NoError(t, os.WriteFile(file, []byte(`url: http://localhost:8000
+include:
+  - `+include+`
+build:
+  disable_asset_copy: yes please
+deployment:
+  extension-managment:
+    enabled: true
+dump:
+  nodata:
+    - cart
+`), os.ModePerm))
+
+	assert.NoError(t, os.WriteFile(include, []byte(`sync:
+  enabled:
+    - theme
+    - themes
+  entity:
+    - entity: tax
+      exists:
+        - type: equals
+          field: name
+          value: Tax
+        - type: multi
+          queries:
+            - type: prefix
+        - type: equals
+      payload:
+        name: Tax
+  snippet:
+    - set: BASE de-DE
+`), os.ModePerm))
+
+	problems, err := ValidateConfig(file)
+	assert.NoError(t, err)
+
+	messages := make([]string, 0, len(problems))
+
+	for _, problem := range problems {
+		messages = append(messages, problem.Error())
+	}
+
+	assert.Equal(t, []string{
+		file + `:5:23: build.disable_asset_copy: expected a boolean, got "yes please"`,
+		file + `:7:3: deployment: unknown key "extension-managment", did you mean "extension-management"?`,
+		include + `:4:7: sync.enabled[1]: invalid value "themes", allowed values are system_config, mail_template, theme, entity, snippet, cms, rule, flow`,
+		include + `:13:15: sync.entity[0].exists[1].queries[0]: missing required key "field"`,
+		include + `:14:11: sync.entity[0].exists[2]: missing required key "field"`,
+		include + `:18:7: sync.snippet[0]: missing required key "file"`,
+	}, messages)
+
+	t.Run("read config fails on problems", func(t *testing.T) {
+		_, err := ReadConfig(include, false)
+
+		assert.ErrorContains(t, err, include+`:4:7: sync.enabled[1]: invalid value "themes"`)
+	})
+
+	t.Run("valid config", func(t *testing.T) {
+		valid := filepath.Join(dir, "valid.yml")
+
+		assert.NoError(t, os.WriteFile(valid, []byte(`url: http://localhost:8000
+admin_api:
+  disable_ssl_check: true
+sync:
+  theme:
+    - name: Storefront
+      settings:
+        sw-color-brand-primary:
+          value: "#000"
+`), os.ModePerm))
+
+		problems, err := ValidateConfig(valid)
+		assert.NoError(t, err)
+		assert.Empty(t, problems)
+	})
+
+	t.Run("anchors and merge keys", func(t *testing.T) {
+		merged := filepath.Join(dir, "merged.yml")
+
+		assert.NoError(t, os.WriteFile(merged, []byte(`url: http://localhost:8000
+admin_api: &api
+  client_id: local
+  client_secret: local-secret
+  disable_ssl_checks: true
+environments:
+  staging:
+    admin_api:
+      <<: *api
+      client_id: staging
+  production:
+    admin_api:
+      <<: [*api]
+      client_secret: 1
+`), os.ModePerm))
+
+		problems, err := ValidateConfig(merged)
+		assert.NoError(t, err)
+
+		messages := make([]string, 0, len(problems))
+
+		for _, problem := range problems {
+			messages = append(messages, problem.Error())
+		}
+
+		assert.Equal(t, []string{
+			merged + `:5:3: admin_api: unknown key "disable_ssl_checks", did you mean "disable_ssl_check"?`,
+		}, messages)
+
+		assert.NoError(t, os.WriteFile(merged, []byte(`url: http://localhost:8000
+admin_api: &api
+  client_id: local
+  client_secret: local-secret
+environments:
+  staging:
+    admin_api:
+      <<: *api
+      client_id: staging
+`), os.ModePerm))
+
+		cfg, err := ReadConfig(merged, false)
+		assert.NoError(t, err)
+		assert.NoError(t, cfg.UseEnvironment("staging"))
+		assert.Equal(t, "staging", cfg.AdminApi.ClientId)
+		assert.Equal(t, "local-secret", cfg.AdminApi.ClientSecret)
+	})
+}
@@ -0,0 +1,385 @@
+package shop
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Clone returns a deep copy of the sync config.
+func (c *ConfigSync) Clone() (*ConfigSync, error) {
+	content, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := &ConfigSync{}
+
+	if err := yaml.Unmarshal(content, clone); err != nil {
+		return nil, err
+	}
+
+	return clone, nil
+}
+
+// WriteSyncConfig writes the sync sections, which differ between original and updated, into the config file defining them.
+// The files are edited in place, so comments, key order and environment variable placeholders of unchanged values are kept.
+// It returns the written files.
+func WriteSyncConfig(fileName string, original, updated *ConfigSync) ([]string, error) {
+	originalNode, err := syncSectionNodes(original)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedNode, err := syncSectionNodes(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := configFiles(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make(map[string]*yaml.Node)
+	changed := make(map[string]bool)
+
+	for _, key := range syncSectionKeys(originalNode, updatedNode) {
+		before, _ := yamlNodeString(originalNode[key])
+		after, _ := yamlNodeString(updatedNode[key])
+
+		if before == after {
+			continue
+		}
+
+		target := fileName
+
+		for _, file := range files {
+			document, err := readConfigDocument(file, documents)
+			if err != nil {
+				return nil, err
+			}
+
+			if mappingValue(mappingValue(document.Content[0], "sync"), key) != nil {
+				target = file
+
+				break
+			}
+		}
+
+		document, err := readConfigDocument(target, documents)
+		if err != nil {
+			return nil, err
+		}
+
+		if mappingValue(document.Content[0], sopsMetadataKey) != nil {
+			return nil, fmt.Errorf("cannot write sync.%s into %s, the file is encrypted with sops", key, target)
+		}
+
+		setSyncSection(document.Content[0], key, updatedNode[key])
+		changed[target] = true
+	}
+
+	written := make([]string, 0, len(changed))
+
+	for _, file := range files {
+		if !changed[file] {
+			continue
+		}
+
+		if err := writeConfigDocument(file, documents[file]); err != nil {
+			return nil, err
+		}
+
+		written = append(written, file)
+	}
+
+	return written, nil
+}
+
+// configFiles returns the config file and its includes, in the order their values take precedence.
+func configFiles(fileName string) ([]string, error) {
+	files := []string{fileName}
+
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		Include []string `yaml:"include"`
+	}
+
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(content))), &config); err != nil {
+		return nil, err
+	}
+
+	for _, include := range config.Include {
+		included, err := configFiles(include)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, included...)
+	}
+
+	return files, nil
+}
+
+func syncSectionNodes(sync *ConfigSync) (map[string]*yaml.Node, error) {
+	nodes := make(map[string]*yaml.Node)
+
+	if sync == nil {
+		return nodes, nil
+	}
+
+	var node yaml.Node
+
+	if err := node.Encode(sync); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		nodes[node.Content[i].Value] = node.Content[i+1]
+	}
+
+	return nodes, nil
+}
+
+func syncSectionKeys(original, updated map[string]*yaml.Node) []string {
+	keys := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, nodes := range []map[string]*yaml.Node{original, updated} {
+		for key := range nodes {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys
+}
+
+func yamlNodeString(node *yaml.Node) (string, error) {
+	if node == nil {
+		return "", nil
+	}
+
+	content, err := yaml.Marshal(node)
+
+	return string(content), err
+}
+
+func readConfigDocument(file string, documents map[string]*yaml.Node) (*yaml.Node, error) {
+	if document, ok := documents[file]; ok {
+		return document, nil
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", file, err)
+	}
+
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	if document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("cannot update %s, the config is not a map", file)
+	}
+
+	documents[file] = &document
+
+	return &document, nil
+}
+
+func writeConfigDocument(file string, document *yaml.Node) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(detectYamlIndent(string(content)))
+
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, buf.Bytes(), os.ModePerm)
+}
+
+// detectYamlIndent returns the indentation of the first indented map key.
+func detectYamlIndent(content string) int {
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+
+		if trimmed == "" || trimmed == line || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "-") {
+			continue
+		}
+
+		return len(line) - len(trimmed)
+	}
+
+	return 2
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// setSyncSection replaces sync.<key> with the value, a nil value removes the key.
+func setSyncSection(root *yaml.Node, key string, value *yaml.Node) {
+	sync := mappingValue(root, "sync")
+
+	if sync == nil || sync.Kind != yaml.MappingNode {
+		if value == nil {
+			return
+		}
+
+		sync = &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(root, "sync", sync)
+	}
+
+	if value == nil {
+		for i := 0; i+1 < len(sync.Content); i += 2 {
+			if sync.Content[i].Value == key {
+				sync.Content = append(sync.Content[:i], sync.Content[i+2:]...)
+
+				return
+			}
+		}
+
+		return
+	}
+
+	setMappingValue(sync, key, mergeYamlNode(mappingValue(sync, key), value))
+}
+
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+
+			return
+		}
+	}
+
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+var envPlaceholderPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandEnvPlaceholders replaces only ${NAME} placeholders with the environment variable, other dollar signs are kept as they are.
+func ExpandEnvPlaceholders(value string) string {
+	return envPlaceholderPattern.ReplaceAllStringFunc(value, func(placeholder string) string {
+		return os.Getenv(envPlaceholderPattern.FindStringSubmatch(placeholder)[1])
+	})
+}
+
+// MergeYamlNode returns the updated node, but keeps comments, placeholders and secret references of the existing node where the value did not change.
+func MergeYamlNode(existing, updated *yaml.Node) *yaml.Node {
+	return mergeYamlNode(existing, updated)
+}
+
+// mergeYamlNode returns the updated node, but keeps the nodes of the existing tree where the value did not change.
+// A scalar with an environment variable placeholder or a secret reference is kept, when its value matches the new value.
+func mergeYamlNode(existing, updated *yaml.Node) *yaml.Node {
+	if existing == nil {
+		return updated
+	}
+
+	if existing.Kind != updated.Kind {
+		copyYamlComments(existing, updated)
+
+		return updated
+	}
+
+	switch updated.Kind {
+	case yaml.ScalarNode:
+		if existing.Value == updated.Value && existing.ShortTag() == updated.ShortTag() {
+			return existing
+		}
+
+		if strings.Contains(existing.Value, "$") && (os.ExpandEnv(existing.Value) == updated.Value || ExpandEnvPlaceholders(existing.Value) == updated.Value) {
+			return existing
+		}
+
+		if secret, ok := lookupResolvedSecret(existing.Value); ok && secret == updated.Value {
+			return existing
+		}
+	case yaml.MappingNode:
+		merged := *existing
+		merged.Content = make([]*yaml.Node, 0, len(updated.Content))
+
+		added := make(map[string]bool)
+
+		for i := 0; i+1 < len(existing.Content); i += 2 {
+			key := existing.Content[i].Value
+
+			if value := mappingValue(updated, key); value != nil {
+				merged.Content = append(merged.Content, existing.Content[i], mergeYamlNode(existing.Content[i+1], value))
+				added[key] = true
+			}
+		}
+
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			if !added[updated.Content[i].Value] {
+				merged.Content = append(merged.Content, updated.Content[i], updated.Content[i+1])
+			}
+		}
+
+		return &merged
+	case yaml.SequenceNode:
+		merged := *existing
+		merged.Content = make([]*yaml.Node, 0, len(updated.Content))
+
+		for i, item := range updated.Content {
+			if i < len(existing.Content) {
+				item = mergeYamlNode(existing.Content[i], item)
+			}
+
+			merged.Content = append(merged.Content, item)
+		}
+
+		return &merged
+	}
+
+	copyYamlComments(existing, updated)
+
+	return updated
+}
+
+func copyYamlComments(from, to *yaml.Node) {
+	to.HeadComment = from.HeadComment
+	to.LineComment = from.LineComment
+	to.FootComment = from.FootComment
+}
@@ -0,0 +1,103 @@
+package shop
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSyncConfig(t *testing.T) {
+	t.Setenv("SHOP_SECRET", "secret")
+	t.Setenv("SHOP_NAME", "Demo")
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, ".shopware-project.yml")
+	include := filepath.Join(dir, "sync.yml")
+
+	assert.NoError(t, os.WriteFile(file, []byte(`# Local shop
+url: http://localhost:8000
+admin_api:
+  client_id: local
+  client_secret: ${SHOP_SECRET} # from the environment
+include:
+  - `+include+`
+`), os.ModePerm))
+
+	assert.NoError(t, os.WriteFile(include, []byte(`sync:
+  config:
+    - settings:
+        # shop name
+        core.basicInformation.shopName: ${SHOP_NAME}
+        core.basicInformation.email: old@example.com
+  snippet:
+    - set: BASE de-DE
+      file: snippets/de-DE.json
+`), os.ModePerm))
+
+	cfg, err := ReadConfig(file, false)
+	assert.NoError(t, err)
+
+	original, err := cfg.Sync.Clone()
+	assert.NoError(t, err)
+
+	cfg.Sync.Config[0].Settings["core.basicInformation.email"] = "new@example.com"
+	cfg.Sync.Snippet = append(cfg.Sync.Snippet, SnippetSync{Set: "BASE en-GB", File: "snippets/en-GB.json"})
+	cfg.Sync.Rule = []RuleDefinition{{File: "rules/always.yml"}}
+
+	written, err := WriteSyncConfig(file, original, cfg.Sync)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{file, include}, written)
+
+	content, err := os.ReadFile(file)
+	assert.NoError(t, err)
+
+	assert.Equal(t, `# Local shop
+url: http://localhost:8000
+admin_api:
+  client_id: local
+  client_secret: ${SHOP_SECRET} # from the environment
+include:
+  - `+include+`
+sync:
+  rule:
+    - file: rules/always.yml
+`, string(content))
+
+	content, err = os.ReadFile(include)
+	assert.NoError(t, err)
+
+	assert.Equal(t, `sync:
+  config:
+    - settings:
+        # shop name
+        core.basicInformation.shopName: ${SHOP_NAME}
+        core.basicInformation.email: new@example.com
+  snippet:
+    - set: BASE de-DE
+      file: snippets/de-DE.json
+    - set: BASE en-GB
+      file: snippets/en-GB.json
+`, string(content))
+
+	t.Run("unchanged config is not written", func(t *testing.T) {
+		cfg, err := ReadConfig(file, false)
+		assert.NoError(t, err)
+
+		written, err := WriteSyncConfig(file, cfg.Sync, cfg.Sync)
+		assert.NoError(t, err)
+		assert.Empty(t, written)
+	})
+}
+
+func TestDetectYamlIndent(t *testing.T) {
+	assert.Equal(t, 2, detectYamlIndent("url: foo\n"))
+	assert.Equal(t, 4, detectYamlIndent("# comment\n    # indented comment\nsync:\n    - foo\n    config:\n        x: y\n"))
+}
+
+func TestExpandEnvPlaceholders(t *testing.T) {
+	t.Setenv("SHOP_NAME", "Demo")
+
+	assert.Equal(t, "Demo costs $5 and $PRICE", ExpandEnvPlaceholders("${SHOP_NAME} costs $5 and $PRICE"))
+}
@@ -87,3 +87,27 @@ func determineByComposerJson(composerJson string) (bool, error) {
 
 	return false, ErrShopwareDependencyNotFound
 }
+
+func GetShopwareVersion(projectRoot string) (string, error) {
+	bytes, err := os.ReadFile(path.Join(projectRoot, "composer.lock"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrNoComposerFileFound
+		}
+
+		return "", err
+	}
+
+	var lock composerLockStruct
+	if err := json.Unmarshal(bytes, &lock); err != nil {
+		return "", err
+	}
+
+	for _, pkg := range lock.Packages {
+		if pkg.Name == "shopware/core" {
+			return pkg.Version, nil
+		}
+	}
+
+	return "", ErrShopwareDependencyNotFound
+}
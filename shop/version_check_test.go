@@ -127,3 +127,31 @@ func TestComposerLockNoDependency(t *testing.T) {
 	assert.ErrorIs(t, err, ErrNoComposerFileFound)
 	assert.False(t, val)
 }
+
+func TestGetShopwareVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	_, err := GetShopwareVersion(tmpDir)
+	assert.ErrorIs(t, err, ErrNoComposerFileFound)
+
+	jsonStruct := composerLockStruct{
+		Packages: []struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}{
+			{
+				Name:    "shopware/core",
+				Version: "6.6.1.0",
+			},
+		},
+	}
+
+	bytes, _ := json.Marshal(jsonStruct)
+
+	_ = os.WriteFile(path.Join(tmpDir, "composer.lock"), bytes, os.ModePerm)
+
+	val, err := GetShopwareVersion(tmpDir)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "6.6.1.0", val)
+}